@@ -0,0 +1,253 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/orfjackal/gospec/src/gospec"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const gospecImportPath = "github.com/orfjackal/gospec/src/gospec"
+
+// Separates the parameters which are forwarded to the specs.
+func splitArgs(args []string) (own []string, forwarded []string) {
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-gospec.") || strings.HasPrefix(arg, "--gospec.") {
+			forwarded = append(forwarded, arg)
+		} else {
+			own = append(own, arg)
+		}
+	}
+	return
+}
+
+// The parameters for writing the results to files are not forwarded to the
+// specs, because the specs of every package would write the same files.
+// Instead the files are written once from the combined results. When
+// re-running the failed specs, the specs must read the failures file.
+var resultFileParams = []string{"gospec.results", "gospec.junit", "gospec.test2json", "gospec.failures"}
+
+func splitResultFileArgs(forwarded []string) (perPackage []string, resultFiles []string, err error) {
+	rerun := false
+	for _, arg := range forwarded {
+		rerun = rerun || paramName(arg) == "gospec.rerun-failed"
+	}
+	for _, arg := range forwarded {
+		name := paramName(arg)
+		switch {
+		case name == "gospec.events":
+			return nil, nil, fmt.Errorf("-gospec.events is not supported, because the specs of every package would write the same file")
+		case contains(resultFileParams, name) && !(rerun && name == "gospec.failures"):
+			resultFiles = append(resultFiles, arg)
+		default:
+			perPackage = append(perPackage, arg)
+		}
+	}
+	return
+}
+
+func paramName(arg string) string {
+	name := strings.TrimLeft(arg, "-")
+	if eq := strings.Index(name, "="); eq >= 0 {
+		name = name[:eq]
+	}
+	return name
+}
+
+// Sets the parameters in GoSpec's own flags, which are in flag.CommandLine,
+// and writes the files they request.
+func saveResultFiles(results *gospec.ResultCollector, resultFiles []string) error {
+	if len(resultFiles) == 0 {
+		return nil
+	}
+	if err := flag.CommandLine.Parse(resultFiles); err != nil {
+		return err
+	}
+	return gospec.SaveResultFiles(results, gospec.DefaultTestName)
+}
+
+// A package as reported by go list.
+type goPackage struct {
+	ImportPath   string
+	Dir          string
+	Deps         []string
+	TestImports  []string
+	XTestImports []string
+}
+
+func (p *goPackage) hasSpecs() bool {
+	return contains(p.TestImports, gospecImportPath) || contains(p.XTestImports, gospecImportPath)
+}
+
+// Tells whether the package or its tests depend on the other package.
+func (p *goPackage) dependsOn(importPath string) bool {
+	return p.ImportPath == importPath ||
+		contains(p.Deps, importPath) ||
+		contains(p.TestImports, importPath) ||
+		contains(p.XTestImports, importPath)
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func listPackages(patterns []string) ([]*goPackage, error) {
+	args := append([]string{"list", "-e", "-json"}, patterns...)
+	cmd := exec.Command("go", args...)
+	cmd.Stderr = os.Stderr
+	output, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	packages, err := parsePackages(output)
+	if waitErr := cmd.Wait(); err == nil && waitErr != nil {
+		err = fmt.Errorf("go list: %v", waitErr)
+	}
+	return packages, err
+}
+
+// Parses the output of go list -json, which is a stream of JSON objects.
+func parsePackages(in io.Reader) ([]*goPackage, error) {
+	packages := []*goPackage{}
+	decoder := json.NewDecoder(in)
+	for decoder.More() {
+		pkg := &goPackage{}
+		if err := decoder.Decode(pkg); err != nil {
+			return nil, err
+		}
+		packages = append(packages, pkg)
+	}
+	return packages, nil
+}
+
+// Returns the import paths of the packages whose tests import GoSpec.
+func specPackages(packages []*goPackage) []string {
+	result := []string{}
+	for _, pkg := range packages {
+		if pkg.hasSpecs() {
+			result = append(result, pkg.ImportPath)
+		}
+	}
+	return result
+}
+
+// Executing the specs of one package.
+type packageTest struct {
+	pkg         string
+	args        []string // parameters for the specs
+	resultsFile string
+	output      []byte
+	err         error
+	results     *gospec.ResultCollector
+}
+
+func newPackageTest(pkg string, args []string) *packageTest {
+	return &packageTest{pkg: pkg, args: args}
+}
+
+func newPackageTests(packages []string, args []string) []*packageTest {
+	tests := []*packageTest{}
+	for _, pkg := range packages {
+		tests = append(tests, newPackageTest(pkg, args))
+	}
+	return tests
+}
+
+// Executes the specs of the packages, at most jobs packages at a time.
+func testPackages(tests []*packageTest, jobs int) error {
+	dir, err := os.MkdirTemp("", "gospec")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	for i, test := range tests {
+		test.resultsFile = filepath.Join(dir, fmt.Sprintf("%d.json", i))
+	}
+	if jobs < 1 {
+		jobs = 1
+	}
+	var wg sync.WaitGroup
+	queue := make(chan *packageTest)
+	for i := 0; i < jobs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for test := range queue {
+				test.run()
+			}
+		}()
+	}
+	for _, test := range tests {
+		queue <- test
+	}
+	close(queue)
+	wg.Wait()
+	return nil
+}
+
+func (test *packageTest) run() {
+	args := []string{"test", test.pkg, "-args", "-gospec.results=" + test.resultsFile}
+	args = append(args, test.args...)
+	cmd := exec.Command("go", args...)
+	test.output, test.err = cmd.CombinedOutput()
+	test.loadResults()
+}
+
+// A package whose tests pass without writing the results has no specs.
+func (test *packageTest) loadResults() {
+	var err error
+	test.results, err = loadResultsFile(test.resultsFile)
+	if err != nil && test.err == nil {
+		test.err = fmt.Errorf("the specs wrote no results, because the tests do not call gospec.MainGoTest or gospec.MainGoSubtests: %v", err)
+	}
+}
+
+func loadResultsFile(path string) (*gospec.ResultCollector, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	return gospec.LoadResults(in)
+}
+
+// Combines the results of all the packages. A package fails also when
+// go test fails without the specs having been executed, for example
+// because of a compile error, or when the specs wrote no results, in which
+// case the error and the output of go test are printed to explain the
+// failure.
+func combineResults(tests []*packageTest, stderr io.Writer) (results *gospec.ResultCollector, failed bool) {
+	results = gospec.NewResultCollector()
+	for _, test := range tests {
+		if test.err != nil {
+			failed = true
+		}
+		if test.err != nil && test.results == nil {
+			fmt.Fprintf(stderr, "%v: %v\n%s", test.pkg, test.err, test.output)
+		} else if *verbose {
+			fmt.Fprintf(stderr, "%v\n%s", test.pkg, test.output)
+		}
+		if test.results != nil {
+			results.Merge(test.results)
+		}
+	}
+	return results, failed
+}
@@ -0,0 +1,88 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"github.com/orfjackal/gospec/src/gospec"
+	. "github.com/orfjackal/gospec/src/gospec"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func GoTestSpec(c gospec.Context) {
+
+	c.Specify("GoSpec's parameters are forwarded to the specs", func() {
+		own, forwarded := splitArgs([]string{"-print-all", "-gospec.focus=Stack", "./...", "--gospec.serial"})
+		c.Expect(own, ContainsInOrder, Values("-print-all", "./..."))
+		c.Expect(forwarded, ContainsInOrder, Values("-gospec.focus=Stack", "--gospec.serial"))
+	})
+
+	c.Specify("Only the packages whose tests import GoSpec are executed", func() {
+		output := `{"ImportPath": "example.com/a", "TestImports": ["fmt", "` + gospecImportPath + `"]}
+			{"ImportPath": "example.com/b", "TestImports": ["fmt", "testing"]}
+			{"ImportPath": "example.com/c"}
+			{"ImportPath": "example.com/d", "XTestImports": ["` + gospecImportPath + `"]}`
+		packages, err := parsePackages(strings.NewReader(output))
+		c.Assume(err, IsNil)
+		c.Expect(specPackages(packages), ContainsInOrder, Values("example.com/a", "example.com/d"))
+	})
+
+	c.Specify("When combining the results of packages", func() {
+		loadResults := func(json string) *gospec.ResultCollector {
+			results, err := gospec.LoadResults(strings.NewReader(json))
+			c.Assume(err, IsNil)
+			return results
+		}
+		stderr := new(bytes.Buffer)
+
+		c.Specify("the specs of all packages are in the same results", func() {
+			tests := []*packageTest{
+				{pkg: "a", results: loadResults(`{"specs": [{"name": "a.ASpec", "status": "passed"}]}`)},
+				{pkg: "b", results: loadResults(`{"specs": [{"name": "b.BSpec", "status": "passed"}]}`)},
+			}
+			results, failed := combineResults(tests, stderr)
+			c.Expect(results.PassCount(), Equals, 2)
+			c.Expect(failed, IsFalse)
+		})
+		c.Specify("a package without results fails and its output is shown", func() {
+			tests := []*packageTest{
+				{pkg: "a", output: []byte("compile error\n"), err: errors.New("exit status 1")},
+			}
+			results, failed := combineResults(tests, stderr)
+			c.Expect(results.TotalCount(), Equals, 0)
+			c.Expect(failed, IsTrue)
+			c.Expect(stderr.String(), Equals, "a: exit status 1\ncompile error\n")
+		})
+		c.Specify("a package which does not write the results fails", func() {
+			test := &packageTest{pkg: "a", resultsFile: filepath.Join(os.TempDir(), "gospec-no-such-file.json")}
+			test.loadResults()
+			_, failed := combineResults([]*packageTest{test}, stderr)
+			c.Expect(failed, IsTrue)
+			c.Expect(stderr.String(), Satisfies, strings.Contains(stderr.String(), "the specs wrote no results"))
+		})
+	})
+
+	c.Specify("The parameters for writing the result files are not forwarded to the packages", func() {
+		perPackage, resultFiles, err := splitResultFileArgs([]string{"-gospec.focus=Stack", "-gospec.junit=report.xml", "--gospec.results=results.json", "-gospec.failures=failures.txt"})
+		c.Assume(err, IsNil)
+		c.Expect(perPackage, ContainsExactly, Values("-gospec.focus=Stack"))
+		c.Expect(resultFiles, ContainsExactly, Values("-gospec.junit=report.xml", "--gospec.results=results.json", "-gospec.failures=failures.txt"))
+	})
+
+	c.Specify("When re-running the failed specs, the failures file is forwarded to the packages", func() {
+		perPackage, resultFiles, err := splitResultFileArgs([]string{"-gospec.rerun-failed", "-gospec.failures=failures.txt"})
+		c.Assume(err, IsNil)
+		c.Expect(perPackage, ContainsExactly, Values("-gospec.rerun-failed", "-gospec.failures=failures.txt"))
+		c.Expect(len(resultFiles), Equals, 0)
+	})
+
+	c.Specify("Streaming the events of many packages to one file is not supported", func() {
+		_, _, err := splitResultFileArgs([]string{"-gospec.events=events.json"})
+		c.Expect(err, Not(IsNil))
+	})
+}
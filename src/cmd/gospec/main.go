@@ -0,0 +1,182 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+// Gospec executes the specs of multiple packages with go test, and prints
+// their results as one combined report. Only the packages whose tests
+// import GoSpec are executed.
+//
+// Usage:
+//
+//	gospec [flags] [packages]
+//
+// The packages are given the same way as to go test, and they default to
+// "./...". GoSpec's own parameters, such as -gospec.focus=regexp, are
+// forwarded to the specs. They must be given in the -flag=value form. The
+// files requested with -gospec.results, -gospec.junit, -gospec.test2json
+// and -gospec.failures are written once from the combined results of all
+// the packages.
+//
+// With -watch, the specs of the packages which are affected by changed
+// files are executed again, until the user quits. On a terminal the keys
+// take effect without pressing Enter: f re-runs the failing specs, a runs
+// all specs and q quits.
+//
+// The merge subcommand combines the results of multiple runs, for example
+// of the shards of a suite which was split with -gospec.shard, and prints
+// them as one report:
+//
+//	gospec merge [flags] results.json...
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/orfjackal/gospec/src/gospec"
+	"io"
+	"os"
+	"runtime"
+	"time"
+)
+
+// Shared with the merge subcommand.
+const colorUsage = "color the default format: `mode` is always, never or auto, which colors it on a terminal unless NO_COLOR is set"
+
+// The gospec package defines its own flags in flag.CommandLine,
+// so this command uses a separate FlagSet.
+var (
+	flags    = flag.NewFlagSet("gospec", flag.ExitOnError)
+	printAll = flags.Bool("print-all", false, "print also passing specs and not only failing")
+	format   = flags.String("format", "default", "format of the report: default, simple, tap, json or test2json")
+	verbose  = flags.Bool("v", false, "print also the output of go test")
+	jobs     = flags.Int("jobs", runtime.NumCPU(), "number of packages to test concurrently")
+	slowest  = flags.Int("slowest", 0, "list the `n` slowest specs and root specs after the summary")
+	color    = flags.String("color", "auto", colorUsage)
+
+	watchMode     = flags.Bool("watch", false, "re-run the specs of the packages affected by changed files")
+	watchInterval = flags.Duration("watch-interval", 500*time.Millisecond, "how often to check for changed files in watch mode")
+)
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "merge" {
+		os.Exit(runMerge(os.Args[2:], os.Stdout))
+	}
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: gospec [flags] [packages]\n       gospec merge [flags] results.json...\n")
+		flags.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "  -gospec.*=value\n    \tforwarded to the specs, see go test -args -help\n")
+	}
+	ownArgs, forwarded := splitArgs(os.Args[1:])
+	flags.Parse(ownArgs)
+	packages := flags.Args()
+	if len(packages) == 0 {
+		packages = []string{"./..."}
+	}
+	os.Exit(run(packages, forwarded, os.Stdout))
+}
+
+func run(patterns []string, forwarded []string, out io.Writer) int {
+	report, err := newReport(*format, out)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	forwarded, resultFiles, err := splitResultFileArgs(forwarded)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	packages, err := listPackages(patterns)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if *watchMode {
+		if len(resultFiles) > 0 {
+			fmt.Fprintln(os.Stderr, "the result files are not written with -watch")
+			return 2
+		}
+		return watch(patterns, packages, forwarded, out, report, os.Stdin)
+	}
+	tests := newPackageTests(specPackages(packages), forwarded)
+	if err := testPackages(tests, *jobs); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	results, failed := combineResults(tests, os.Stderr)
+	results.ReportSlowest(*slowest)
+	if err := report(results, *printAll); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if err := saveResultFiles(results, resultFiles); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	if failed || results.FailCount() > 0 {
+		return 1
+	}
+	return 0
+}
+
+// Reports the combined results, either by printing them with a PrintFormat,
+// or with the json format by writing them in the same form as the
+// -gospec.results files, which is documented in GoSpec's README, or with
+// the test2json format as the events of go test -json. The tap format
+// prints always all specs, because TAP consumers expect every test.
+type reportFunc func(results *gospec.ResultCollector, all bool) error
+
+func newReport(name string, out io.Writer) (reportFunc, error) {
+	switch name {
+	case "json":
+		return func(results *gospec.ResultCollector, all bool) error {
+			return results.Save(out)
+		}, nil
+	case "test2json":
+		return func(results *gospec.ResultCollector, all bool) error {
+			return results.WriteTestEvents(out, gospec.DefaultTestName)
+		}, nil
+	case "tap":
+		return func(results *gospec.ResultCollector, all bool) error {
+			printResults(results, gospec.TapPrintFormat(out), true)
+			return nil
+		}, nil
+	}
+	printFormat, err := newPrintFormat(name, out)
+	if err != nil {
+		return nil, err
+	}
+	return func(results *gospec.ResultCollector, all bool) error {
+		printResults(results, printFormat, all)
+		return nil
+	}, nil
+}
+
+func printResults(results *gospec.ResultCollector, printFormat gospec.PrintFormat, all bool) {
+	printer := gospec.NewPrinter(printFormat)
+	if all {
+		printer.ShowAll()
+	} else {
+		printer.ShowOnlyFailing()
+	}
+	results.Visit(printer)
+}
+
+func newPrintFormat(name string, out io.Writer) (gospec.PrintFormat, error) {
+	switch name {
+	case "default":
+		colors, err := gospec.UseColors(*color, out)
+		if err != nil {
+			return nil, err
+		}
+		if colors {
+			return gospec.ColorPrintFormat(out), nil
+		}
+		return gospec.DefaultPrintFormat(out), nil
+	case "simple":
+		return gospec.SimplePrintFormat(out), nil
+	}
+	return nil, fmt.Errorf("unknown format: %v", name)
+}
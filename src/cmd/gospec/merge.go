@@ -0,0 +1,81 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/orfjackal/gospec/src/gospec"
+	"io"
+	"os"
+)
+
+// Combines the results files which were written with -gospec.results.
+func runMerge(args []string, out io.Writer) int {
+	mergeFlags := flag.NewFlagSet("gospec merge", flag.ExitOnError)
+	printAll := mergeFlags.Bool("print-all", false, "print also passing specs and not only failing")
+	format := mergeFlags.String("format", "default", "format of the report: default, simple, tap, json or test2json")
+	mergeFlags.StringVar(color, "color", "auto", colorUsage)
+	output := mergeFlags.String("o", "", "write also the merged results as JSON to `file`")
+	mergeFlags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: gospec merge [flags] results.json...\n")
+		mergeFlags.PrintDefaults()
+	}
+	mergeFlags.Parse(args)
+	if mergeFlags.NArg() == 0 {
+		mergeFlags.Usage()
+		return 2
+	}
+	report, err := newReport(*format, out)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	results, err := mergeResultsFiles(mergeFlags.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if err := report(results, *printAll); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if *output != "" {
+		if err := saveResultsFile(*output, results); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	}
+	if results.FailCount() > 0 {
+		return 1
+	}
+	return 0
+}
+
+// The results of the same specs are merged, so that a spec fails if it
+// failed on any of the runs, and the same errors are reported only once.
+func mergeResultsFiles(paths []string) (*gospec.ResultCollector, error) {
+	results := gospec.NewResultCollector()
+	for _, path := range paths {
+		other, err := loadResultsFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", path, err)
+		}
+		results.Merge(other)
+	}
+	return results, nil
+}
+
+func saveResultsFile(path string, results *gospec.ResultCollector) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = results.Save(out)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,101 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package main
+
+import (
+	"bytes"
+	"github.com/orfjackal/gospec/src/gospec"
+	. "github.com/orfjackal/gospec/src/gospec"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func MergeSpec(c gospec.Context) {
+	dir, err := os.MkdirTemp("", "gospec")
+	c.Assume(err, IsNil)
+	c.Defer(func() { os.RemoveAll(dir) })
+	writeResults := func(name string, json string) string {
+		path := filepath.Join(dir, name)
+		c.Assume(os.WriteFile(path, []byte(json), 0644), IsNil)
+		return path
+	}
+	shard1 := writeResults("shard1.json", `{"specs": [
+		{"name": "pkg.ASpec", "path": [], "status": "passed", "duration": 2}]}`)
+	shard2 := writeResults("shard2.json", `{"specs": [
+		{"name": "pkg.BSpec", "path": [], "status": "passed", "children": [
+			{"name": "fails", "path": [0], "status": "failed", "errors": [
+				{"type": "OtherError", "message": "boom"}]}]}]}`)
+
+	c.Specify("The results of all the files are merged", func() {
+		results, err := mergeResultsFiles([]string{shard1, shard2})
+		c.Assume(err, IsNil)
+		c.Expect(results.TotalCount(), Equals, 3)
+		c.Expect(results.FailCount(), Equals, 1)
+	})
+
+	c.Specify("The results of the same specs are merged", func() {
+		results, err := mergeResultsFiles([]string{shard2, shard2})
+		c.Assume(err, IsNil)
+		c.Expect(results.TotalCount(), Equals, 2)
+		c.Expect(results.FailCount(), Equals, 1)
+	})
+
+	c.Specify("A file which can not be read is an error", func() {
+		_, err := mergeResultsFiles([]string{shard1, filepath.Join(dir, "missing.json")})
+		c.Expect(err, Not(IsNil))
+	})
+
+	c.Specify("The merged report is printed and the exit code tells whether there were failures", func() {
+		out := new(bytes.Buffer)
+		c.Expect(runMerge([]string{"-format=simple", shard1, shard2}, out), Equals, 1)
+		c.Expect(out.String(), Satisfies, strings.Contains(out.String(), "- fails [FAIL]\n*** boom"))
+		c.Expect(out.String(), Satisfies, strings.HasSuffix(out.String(), "3 specs, 1 failures\n"))
+
+		out.Reset()
+		c.Expect(runMerge([]string{shard1}, out), Equals, 0)
+	})
+
+	c.Specify("The merged results can be saved, for example for balancing the shards", func() {
+		merged := filepath.Join(dir, "merged.json")
+		c.Expect(runMerge([]string{"-o=" + merged, shard1, shard2}, new(bytes.Buffer)), Equals, 1)
+		results, err := mergeResultsFiles([]string{merged})
+		c.Assume(err, IsNil)
+		c.Expect(results.TotalCount(), Equals, 3)
+		content, err := os.ReadFile(merged)
+		c.Assume(err, IsNil)
+		c.Expect(string(content), Satisfies, strings.Contains(string(content), `"duration":2`))
+	})
+
+	c.Specify("The merged results can be printed as JSON", func() {
+		out := new(bytes.Buffer)
+		c.Expect(runMerge([]string{"-format=json", shard1, shard2}, out), Equals, 1)
+		results, err := gospec.LoadResults(out)
+		c.Assume(err, IsNil)
+		c.Expect(results.TotalCount(), Equals, 3)
+		c.Expect(results.FailCount(), Equals, 1)
+	})
+	c.Specify("The merged results can be printed as go test -json events", func() {
+		out := new(bytes.Buffer)
+		c.Expect(runMerge([]string{"-format=test2json", shard1, shard2}, out), Equals, 1)
+		c.Expect(out.String(), Satisfies, strings.Contains(out.String(),
+			`"Action":"fail","Package":"pkg","Test":"TestAllSpecs/BSpec/fails"`))
+	})
+	c.Specify("The merged results can be printed as TAP", func() {
+		out := new(bytes.Buffer)
+		c.Expect(runMerge([]string{"-format=tap", shard1, shard2}, out), Equals, 1)
+		c.Expect(out.String(), Satisfies, strings.HasPrefix(out.String(), "TAP version 13\nok 1 - pkg.ASpec\n"))
+		c.Expect(out.String(), Satisfies, strings.Contains(out.String(), "    not ok 1 - fails\n"))
+	})
+	c.Specify("The default format can be colored", func() {
+		out := new(bytes.Buffer)
+		c.Expect(runMerge([]string{"-color=always", shard1, shard2}, out), Equals, 1)
+		c.Expect(out.String(), Satisfies, strings.Contains(out.String(), "\x1b[31m[FAIL]\x1b[0m"))
+
+		out.Reset()
+		c.Expect(runMerge([]string{"-color=never", shard1, shard2}, out), Equals, 1)
+		c.Expect(out.String(), Satisfies, !strings.Contains(out.String(), "\x1b"))
+	})
+}
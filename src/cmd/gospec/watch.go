@@ -0,0 +1,306 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/orfjackal/gospec/src/gospec"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const watchHelp = "Watching for changes. Press f to re-run the failing specs, a to run all specs, q to quit."
+
+// Watch mode re-runs the specs of the packages which are affected by
+// changes to the .go files, until the user quits. Only the failures
+// and the summary are printed. The packages are listed again after each
+// change, so that new packages and changed imports are noticed.
+type watcher struct {
+	packages  []*goPackage
+	list      func() ([]*goPackage, error)
+	forwarded []string
+	out       io.Writer
+	report    reportFunc
+	modTimes  map[string]time.Time  // of the .go files, by path
+	failing   map[string][][]string // name paths of the failing specs, by package
+}
+
+func newWatcher(packages []*goPackage, forwarded []string, out io.Writer, report reportFunc) *watcher {
+	return &watcher{
+		packages:  packages,
+		forwarded: forwarded,
+		out:       out,
+		report:    report,
+		modTimes:  scanGoFiles(packages),
+		failing:   make(map[string][][]string),
+	}
+}
+
+func watch(patterns []string, packages []*goPackage, forwarded []string, out io.Writer, report reportFunc, stdin *os.File) int {
+	w := newWatcher(packages, forwarded, out, report)
+	w.list = func() ([]*goPackage, error) { return listPackages(patterns) }
+	restore := readKeysWithoutEnter(stdin)
+	defer restore()
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt)
+	defer signal.Stop(interrupted)
+	w.runAll()
+	keys := readKeys(stdin)
+	ticker := time.NewTicker(*watchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if changed := w.changedPackages(); len(changed) > 0 {
+				w.refreshPackages(changed)
+				w.runTests(newPackageTests(w.affectedPackages(changed), w.forwarded))
+			}
+		case key, ok := <-keys:
+			if !ok {
+				return 0
+			}
+			switch key {
+			case 'f':
+				w.rerunFailing()
+			case 'a':
+				w.runAll()
+			case 'q':
+				return 0
+			}
+		case <-interrupted:
+			return 0
+		}
+	}
+}
+
+// Reads the keys one at a time. When the input is not a terminal, the
+// keys may be given one per line.
+func readKeys(in io.Reader) <-chan rune {
+	keys := make(chan rune)
+	go func() {
+		defer close(keys)
+		reader := bufio.NewReader(in)
+		for {
+			key, _, err := reader.ReadRune()
+			if err != nil {
+				return
+			}
+			if !unicode.IsSpace(key) {
+				keys <- key
+			}
+		}
+	}()
+	return keys
+}
+
+// Puts the terminal into cbreak mode, so that a keypress is read without
+// waiting for Enter. Returns a function which restores the previous mode.
+// If the input is not a terminal or stty fails, the keys are read after
+// Enter is pressed.
+func readKeysWithoutEnter(in *os.File) (restore func()) {
+	restore = func() {}
+	if !isTerminal(in) {
+		return
+	}
+	saved, err := stty(in, "-g")
+	if err != nil {
+		return
+	}
+	if _, err := stty(in, "-icanon", "-echo", "min", "1"); err != nil {
+		return
+	}
+	return func() { stty(in, strings.TrimSpace(saved)) }
+}
+
+func stty(terminal *os.File, args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = terminal
+	output, err := cmd.Output()
+	return string(output), err
+}
+
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func (w *watcher) runAll() {
+	w.runTests(newPackageTests(specPackages(w.packages), w.forwarded))
+}
+
+// Re-runs only the failing specs, by focusing on them in each package
+// which had failures.
+func (w *watcher) rerunFailing() {
+	if len(w.failing) == 0 {
+		fmt.Fprintf(w.out, "No failing specs.\n%v\n", watchHelp)
+		return
+	}
+	tests := []*packageTest{}
+	for _, pkg := range sortedKeys(w.failing) {
+		args := append(append([]string{}, w.forwarded...), "-gospec.focus="+focusPattern(w.failing[pkg]))
+		tests = append(tests, newPackageTest(pkg, args))
+	}
+	w.runTests(tests)
+}
+
+func (w *watcher) runTests(tests []*packageTest) {
+	if len(tests) == 0 {
+		return
+	}
+	fmt.Fprintf(w.out, "\nRunning the specs of %v package(s) at %v\n", len(tests), time.Now().Format("15:04:05"))
+	if err := testPackages(tests, *jobs); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	for _, test := range tests {
+		delete(w.failing, test.pkg)
+		if test.results != nil {
+			if paths := failingSpecPaths(test.results); len(paths) > 0 {
+				w.failing[test.pkg] = paths
+			}
+		}
+	}
+	results, _ := combineResults(tests, os.Stderr)
+	if err := w.report(results, false); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	fmt.Fprintln(w.out, watchHelp)
+}
+
+// Returns the import paths of the packages whose .go files have been
+// changed, added or removed since the previous call.
+func (w *watcher) changedPackages() map[string]bool {
+	modTimes := scanGoFiles(w.packages)
+	changedDirs := make(map[string]bool)
+	for path, modTime := range modTimes {
+		if old, found := w.modTimes[path]; !found || !old.Equal(modTime) {
+			changedDirs[filepath.Dir(path)] = true
+		}
+	}
+	for path := range w.modTimes {
+		if _, found := modTimes[path]; !found {
+			changedDirs[filepath.Dir(path)] = true
+		}
+	}
+	w.modTimes = modTimes
+	changed := make(map[string]bool)
+	for _, pkg := range w.packages {
+		if changedDirs[pkg.Dir] {
+			changed[pkg.ImportPath] = true
+		}
+	}
+	return changed
+}
+
+// Lists the packages again, because the change may have added packages or
+// changed their imports. The new packages are added to the changed ones.
+// If listing fails, the previous packages are kept.
+func (w *watcher) refreshPackages(changed map[string]bool) {
+	packages, err := w.list()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	listed := make(map[string]bool)
+	for _, pkg := range w.packages {
+		listed[pkg.ImportPath] = true
+	}
+	current := make(map[string]bool)
+	for _, pkg := range packages {
+		current[pkg.ImportPath] = true
+		if !listed[pkg.ImportPath] {
+			changed[pkg.ImportPath] = true
+		}
+	}
+	for pkg := range w.failing {
+		if !current[pkg] {
+			delete(w.failing, pkg)
+		}
+	}
+	w.packages = packages
+	w.modTimes = scanGoFiles(packages)
+}
+
+// Returns the spec packages which depend on any of the changed packages.
+func (w *watcher) affectedPackages(changed map[string]bool) []string {
+	affected := []string{}
+	for _, pkg := range w.packages {
+		if !pkg.hasSpecs() {
+			continue
+		}
+		for importPath := range changed {
+			if pkg.dependsOn(importPath) {
+				affected = append(affected, pkg.ImportPath)
+				break
+			}
+		}
+	}
+	return affected
+}
+
+func scanGoFiles(packages []*goPackage) map[string]time.Time {
+	modTimes := make(map[string]time.Time)
+	for _, pkg := range packages {
+		paths, _ := filepath.Glob(filepath.Join(pkg.Dir, "*.go"))
+		for _, path := range paths {
+			if info, err := os.Stat(path); err == nil {
+				modTimes[path] = info.ModTime()
+			}
+		}
+	}
+	return modTimes
+}
+
+func sortedKeys(m map[string][][]string) []string {
+	keys := []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Collects the name paths of the failing specs, from the root spec
+// to the failing spec.
+type failingSpecCollector struct {
+	names []string
+	paths [][]string
+}
+
+func failingSpecPaths(results *gospec.ResultCollector) [][]string {
+	collector := new(failingSpecCollector)
+	results.Visit(collector)
+	return collector.paths
+}
+
+func (v *failingSpecCollector) VisitSpec(nestingLevel int, name string, status gospec.Status, errors []*gospec.Error) {
+	v.names = append(v.names[:nestingLevel], name)
+	if status == gospec.Failed {
+		v.paths = append(v.paths, append([]string{}, v.names...))
+	}
+}
+
+func (v *failingSpecCollector) VisitEnd(summary gospec.Summary) {
+}
+
+// Returns a -gospec.focus pattern which matches the whole nested names of
+// the specs on the name paths, so that only they and their children are
+// executed.
+func focusPattern(paths [][]string) string {
+	names := []string{}
+	for _, path := range paths {
+		names = append(names, regexp.QuoteMeta(strings.Join(path, "/")))
+	}
+	return "^(" + strings.Join(names, "|") + ")$"
+}
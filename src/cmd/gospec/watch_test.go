@@ -0,0 +1,119 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package main
+
+import (
+	"github.com/orfjackal/gospec/src/gospec"
+	. "github.com/orfjackal/gospec/src/gospec"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+func WatchSpec(c gospec.Context) {
+
+	c.Specify("The failing specs are re-run by focusing on them", func() {
+
+		c.Specify("the pattern matches the whole nested name", func() {
+			pattern := focusPattern([][]string{{"pkg.RootSpec", "a", "aa"}})
+			c.Expect(pattern, Equals, `^(pkg\.RootSpec/a/aa)$`)
+		})
+		c.Specify("the names of the specs are alternatives", func() {
+			pattern := focusPattern([][]string{{"Root", "a", "aa"}, {"Root", "b"}})
+			c.Expect(pattern, Equals, `^(Root/a/aa|Root/b)$`)
+		})
+		c.Specify("the names may contain slashes", func() {
+			pattern := focusPattern([][]string{{"example.com/pkg.Root", "a/b"}})
+			c.Expect(regexp.MustCompile(pattern).MatchString("example.com/pkg.Root/a/b"), IsTrue)
+			c.Expect(regexp.MustCompile(pattern).MatchString("example.com/pkg.Root/a"), IsFalse)
+		})
+	})
+
+	c.Specify("The name paths of the failing specs are collected from the results", func() {
+		results, err := gospec.LoadResults(strings.NewReader(`{"specs": [
+			{"name": "Root", "path": [], "status": "passed", "children": [
+				{"name": "a", "path": [0], "status": "failed"},
+				{"name": "b", "path": [1], "status": "passed", "children": [
+					{"name": "ba", "path": [1, 0], "status": "failed"}]}]}]}`))
+		c.Assume(err, IsNil)
+		paths := failingSpecPaths(results)
+		c.Expect(len(paths), Equals, 2)
+		c.Expect(paths[0], ContainsInOrder, Values("Root", "a"))
+		c.Expect(paths[1], ContainsInOrder, Values("Root", "b", "ba"))
+	})
+
+	c.Specify("The specs are re-run in the packages affected by a change", func() {
+		packages := []*goPackage{
+			{ImportPath: "lib", TestImports: []string{gospecImportPath}},
+			{ImportPath: "app", Deps: []string{"lib"}, TestImports: []string{gospecImportPath}},
+			{ImportPath: "tool", Deps: []string{"lib"}},
+			{ImportPath: "other", XTestImports: []string{gospecImportPath}},
+		}
+		w := newWatcher(packages, nil, nil, nil)
+
+		c.Specify("the changed package and the packages depending on it", func() {
+			c.Expect(w.affectedPackages(map[string]bool{"lib": true}), ContainsInOrder, Values("lib", "app"))
+		})
+		c.Specify("only if they have specs", func() {
+			c.Expect(len(w.affectedPackages(map[string]bool{"tool": true})), Equals, 0)
+		})
+		c.Specify("the packages are listed again, so that new packages and imports are noticed", func() {
+			w.failing["other"] = [][]string{{"other.OtherSpec"}}
+			w.list = func() ([]*goPackage, error) {
+				return []*goPackage{
+					{ImportPath: "lib", TestImports: []string{gospecImportPath}},
+					{ImportPath: "app", Deps: []string{"lib"}, TestImports: []string{gospecImportPath}},
+					{ImportPath: "tool", Deps: []string{"lib"}, TestImports: []string{gospecImportPath}},
+					{ImportPath: "new", TestImports: []string{gospecImportPath}},
+				}, nil
+			}
+			changed := map[string]bool{"lib": true}
+			w.refreshPackages(changed)
+			c.Expect(w.affectedPackages(changed), ContainsInOrder, Values("lib", "app", "tool", "new"))
+			c.Expect(len(w.failing), Equals, 0)
+		})
+	})
+
+	c.Specify("Changes to the .go files of the packages are detected", func() {
+		dir, err := os.MkdirTemp("", "gospec")
+		c.Assume(err, IsNil)
+		c.Defer(func() { os.RemoveAll(dir) })
+		file := filepath.Join(dir, "a.go")
+		c.Assume(os.WriteFile(file, []byte("package a\n"), 0644), IsNil)
+		w := newWatcher([]*goPackage{{ImportPath: "a", Dir: dir}}, nil, nil, nil)
+
+		c.Specify("nothing has changed at first", func() {
+			c.Expect(len(w.changedPackages()), Equals, 0)
+		})
+		c.Specify("a modified file", func() {
+			later := time.Now().Add(time.Minute)
+			c.Assume(os.Chtimes(file, later, later), IsNil)
+			c.Expect(w.changedPackages()["a"], IsTrue)
+			c.Expect(len(w.changedPackages()), Equals, 0)
+		})
+		c.Specify("an added file", func() {
+			c.Assume(os.WriteFile(filepath.Join(dir, "b.go"), []byte("package a\n"), 0644), IsNil)
+			c.Expect(w.changedPackages()["a"], IsTrue)
+		})
+		c.Specify("a removed file", func() {
+			c.Assume(os.Remove(file), IsNil)
+			c.Expect(w.changedPackages()["a"], IsTrue)
+		})
+		c.Specify("other files are ignored", func() {
+			c.Assume(os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("x"), 0644), IsNil)
+			c.Expect(len(w.changedPackages()), Equals, 0)
+		})
+	})
+
+	c.Specify("The keys are read one at a time", func() {
+		keys := []rune{}
+		for key := range readKeys(strings.NewReader("f\na q")) {
+			keys = append(keys, key)
+		}
+		c.Expect(string(keys), Equals, "faq")
+	})
+}
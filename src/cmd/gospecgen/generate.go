@@ -0,0 +1,189 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+const (
+	gospecImportPath = "github.com/orfjackal/gospec/src/gospec"
+	generatedHeader  = "// Code generated by gospecgen. DO NOT EDIT."
+)
+
+type options struct {
+	output   string // name of the generated file
+	testName string // name of the generated gotest test method
+	subtests bool   // whether to use MainGoSubtests instead of MainGoTest
+}
+
+// The specs of one package, as found from its test files.
+type specPackage struct {
+	name  string
+	specs []string
+}
+
+// Finds the spec functions from the test files in the directory. A spec
+// function is a top-level function whose name ends with "Spec" and which
+// takes gospec.Context as its only parameter.
+func findSpecs(dir string, opts options) (*specPackage, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*_test.go"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	pkg := &specPackage{}
+	fset := token.NewFileSet()
+	for _, path := range paths {
+		if filepath.Base(path) == opts.output {
+			continue
+		}
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		specs := specsInFile(file)
+		if len(specs) == 0 {
+			continue
+		}
+		if pkg.name != "" && pkg.name != file.Name.Name {
+			return nil, fmt.Errorf("%v: specs found in both packages %v and %v", dir, pkg.name, file.Name.Name)
+		}
+		pkg.name = file.Name.Name
+		pkg.specs = append(pkg.specs, specs...)
+	}
+	if len(pkg.specs) == 0 {
+		return nil, fmt.Errorf("%v: no specs found", dir)
+	}
+	return pkg, nil
+}
+
+func specsInFile(file *ast.File) []string {
+	contextType := gospecContextType(file)
+	if contextType == "" {
+		return nil
+	}
+	specs := []string{}
+	for _, decl := range file.Decls {
+		fun, ok := decl.(*ast.FuncDecl)
+		if ok && fun.Recv == nil && isSpecFunction(fun, contextType) {
+			specs = append(specs, fun.Name.Name)
+		}
+	}
+	return specs
+}
+
+// Returns how gospec.Context is referred to in the file, depending on
+// how the gospec package is imported, or "" if it is not imported.
+func gospecContextType(file *ast.File) string {
+	for _, imp := range file.Imports {
+		if path, _ := strconv.Unquote(imp.Path.Value); path != gospecImportPath {
+			continue
+		}
+		switch {
+		case imp.Name == nil:
+			return "gospec.Context"
+		case imp.Name.Name == ".":
+			return "Context"
+		case imp.Name.Name != "_":
+			return imp.Name.Name + ".Context"
+		}
+	}
+	return ""
+}
+
+func isSpecFunction(fun *ast.FuncDecl, contextType string) bool {
+	if !strings.HasSuffix(fun.Name.Name, "Spec") || fun.Type.Results != nil {
+		return false
+	}
+	params := fun.Type.Params.List
+	if len(params) != 1 || len(params[0].Names) > 1 {
+		return false
+	}
+	return typeName(params[0].Type) == contextType
+}
+
+func typeName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.SelectorExpr:
+		return typeName(t.X) + "." + t.Sel.Name
+	}
+	return ""
+}
+
+var registrationTemplate = template.Must(template.New("registration").Parse(`{{.Header}}
+
+package {{.Package}}
+
+import (
+	"github.com/orfjackal/gospec/src/gospec"
+	"testing"
+)
+
+func {{.TestName}}(t *testing.T) {
+	r := gospec.NewRunner()
+{{range .Specs}}	r.AddSpec({{.}})
+{{end}}	gospec.{{.Main}}(r, t)
+}
+`))
+
+// Generates the test file which registers all the specs of the package.
+func generate(pkg *specPackage, opts options) ([]byte, error) {
+	main := "MainGoTest"
+	if opts.subtests {
+		main = "MainGoSubtests"
+	}
+	var buf bytes.Buffer
+	err := registrationTemplate.Execute(&buf, map[string]interface{}{
+		"Header":   generatedHeader,
+		"Package":  pkg.name,
+		"Specs":    pkg.specs,
+		"TestName": opts.testName,
+		"Main":     main,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
+
+// Writes the generated file, but refuses to overwrite a file which was
+// not generated by this tool, such as a hand-written all_specs_test.go.
+func writeGenerated(path string, content []byte) error {
+	old, err := os.ReadFile(path)
+	if err == nil && !isGenerated(old) {
+		return fmt.Errorf("%v: not overwriting a file which was not generated by gospecgen", path)
+	}
+	return os.WriteFile(path, content, 0644)
+}
+
+// Tells whether the file is up to date with the generated content.
+func checkGenerated(path string, content []byte) error {
+	old, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(old, content) {
+		return fmt.Errorf("%v: out of date, run gospecgen to regenerate it", path)
+	}
+	return nil
+}
+
+func isGenerated(content []byte) bool {
+	return bytes.HasPrefix(content, []byte(generatedHeader))
+}
@@ -0,0 +1,138 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package main
+
+import (
+	"github.com/orfjackal/gospec/src/gospec"
+	. "github.com/orfjackal/gospec/src/gospec"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var defaultOptions = options{"all_specs_test.go", "TestAllSpecs", false}
+
+func specsInSource(src string) []string {
+	file, err := parser.ParseFile(token.NewFileSet(), "x_test.go", src, 0)
+	if err != nil {
+		panic(err)
+	}
+	return specsInFile(file)
+}
+
+func GenerateSpec(c gospec.Context) {
+
+	c.Specify("Spec functions are found from test files", func() {
+
+		c.Specify("when gospec is imported normally", func() {
+			c.Expect(specsInSource(`package foo
+import "github.com/orfjackal/gospec/src/gospec"
+func FooSpec(c gospec.Context) {}
+`), ContainsInOrder, Values("FooSpec"))
+		})
+		c.Specify("when gospec is imported with a dot", func() {
+			c.Expect(specsInSource(`package foo
+import . "github.com/orfjackal/gospec/src/gospec"
+func FooSpec(c Context) {}
+`), ContainsInOrder, Values("FooSpec"))
+		})
+		c.Specify("when gospec is imported with another name", func() {
+			c.Expect(specsInSource(`package foo
+import gs "github.com/orfjackal/gospec/src/gospec"
+func FooSpec(c gs.Context) {}
+`), ContainsInOrder, Values("FooSpec"))
+		})
+		c.Specify("in the order in which they are declared", func() {
+			c.Expect(specsInSource(`package foo
+import "github.com/orfjackal/gospec/src/gospec"
+func ZebraSpec(c gospec.Context) {}
+func AardvarkSpec(c gospec.Context) {}
+`), ContainsInOrder, Values("ZebraSpec", "AardvarkSpec"))
+		})
+	})
+
+	c.Specify("Other functions are not spec functions", func() {
+		c.Expect(specsInSource(`package foo
+import "github.com/orfjackal/gospec/src/gospec"
+func NotASpecFunction(c gospec.Context) {}
+func WrongParameterSpec(c int) {}
+func TooManyParametersSpec(c gospec.Context, x int) {}
+func ReturnsSomethingSpec(c gospec.Context) int { return 0 }
+type T struct{}
+func (t T) MethodSpec(c gospec.Context) {}
+`), ContainsInOrder, Values())
+	})
+
+	c.Specify("The generated file registers all the specs", func() {
+		content, err := generate(&specPackage{"foo", []string{"FooSpec", "BarSpec"}}, defaultOptions)
+		c.Assume(err, IsNil)
+		c.Expect(string(content), Equals, `// Code generated by gospecgen. DO NOT EDIT.
+
+package foo
+
+import (
+	"github.com/orfjackal/gospec/src/gospec"
+	"testing"
+)
+
+func TestAllSpecs(t *testing.T) {
+	r := gospec.NewRunner()
+	r.AddSpec(FooSpec)
+	r.AddSpec(BarSpec)
+	gospec.MainGoTest(r, t)
+}
+`)
+	})
+
+	c.Specify("The generated file may report the specs as subtests", func() {
+		opts := defaultOptions
+		opts.subtests = true
+		content, err := generate(&specPackage{"foo", []string{"FooSpec"}}, opts)
+		c.Assume(err, IsNil)
+		c.Expect(string(content), Satisfies, strings.Contains(string(content), "gospec.MainGoSubtests(r, t)"))
+	})
+
+	c.Specify("When generating the file for a directory", func() {
+		dir, err := os.MkdirTemp("", "gospecgen")
+		c.Assume(err, IsNil)
+		c.Defer(func() { os.RemoveAll(dir) })
+		writeFile := func(name string, content string) {
+			c.Assume(os.WriteFile(filepath.Join(dir, name), []byte(content), 0644), IsNil)
+		}
+		output := filepath.Join(dir, "all_specs_test.go")
+		writeFile("b_test.go", "package foo\nimport \"github.com/orfjackal/gospec/src/gospec\"\nfunc BSpec(c gospec.Context) {}\n")
+		writeFile("a_test.go", "package foo\nimport \"github.com/orfjackal/gospec/src/gospec\"\nfunc ASpec(c gospec.Context) {}\n")
+
+		c.Specify("the specs of all test files are registered", func() {
+			c.Assume(run(dir, defaultOptions, false), IsNil)
+			content, _ := os.ReadFile(output)
+			c.Expect(string(content), Satisfies, strings.Contains(string(content), "r.AddSpec(ASpec)\n\tr.AddSpec(BSpec)\n"))
+		})
+		c.Specify("the check passes when the file is up to date", func() {
+			c.Assume(run(dir, defaultOptions, false), IsNil)
+			c.Expect(run(dir, defaultOptions, true), IsNil)
+		})
+		c.Specify("the check fails when a new spec has been added", func() {
+			c.Assume(run(dir, defaultOptions, false), IsNil)
+			writeFile("c_test.go", "package foo\nimport \"github.com/orfjackal/gospec/src/gospec\"\nfunc CSpec(c gospec.Context) {}\n")
+			c.Expect(run(dir, defaultOptions, true), Not(IsNil))
+		})
+		c.Specify("a hand-written file is not overwritten", func() {
+			writeFile("all_specs_test.go", "package foo\n")
+			c.Expect(run(dir, defaultOptions, false), Not(IsNil))
+			content, _ := os.ReadFile(output)
+			c.Expect(string(content), Equals, "package foo\n")
+		})
+	})
+
+	c.Specify("It is an error if there are no specs", func() {
+		dir, err := os.MkdirTemp("", "gospecgen")
+		c.Assume(err, IsNil)
+		c.Defer(func() { os.RemoveAll(dir) })
+		c.Expect(run(dir, defaultOptions, false), Not(IsNil))
+	})
+}
@@ -0,0 +1,66 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+// Gospecgen generates the test file which registers all the specs of a
+// package to GoSpec, so that the specs don't need to be listed by hand.
+// It is meant to be used with go generate, by adding this line to one of
+// the package's files:
+//
+//	//go:generate gospecgen
+//
+// The spec functions are found from the package's test files. They are
+// top-level functions whose name ends with "Spec" and which take
+// gospec.Context as their only parameter.
+//
+// Usage:
+//
+//	gospecgen [flags] [directory]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+var (
+	output   = flag.String("o", "all_specs_test.go", "name of the generated file")
+	testName = flag.String("test", "TestAllSpecs", "name of the generated test method")
+	subtests = flag.Bool("subtests", false, "report the specs as gotest subtests, using gospec.MainGoSubtests")
+	check    = flag.Bool("check", false, "do not write the file, but fail if it is out of date")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: gospecgen [flags] [directory]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+	opts := options{*output, *testName, *subtests}
+	if err := run(dir, opts, *check); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(dir string, opts options, check bool) error {
+	pkg, err := findSpecs(dir, opts)
+	if err != nil {
+		return err
+	}
+	content, err := generate(pkg, opts)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, opts.output)
+	if check {
+		return checkGenerated(path, content)
+	}
+	return writeGenerated(path, content)
+}
@@ -10,10 +10,10 @@ import (
 )
 
 // You will need to list every spec in a TestXxx method like this,
-// so that gotest can be used to run the specs. Later GoSpec might
-// get its own command line tool similar to gotest, but for now this
-// is the way to go. This shouldn't require too much typing, because
-// there will be typically only one top-level spec per class/feature.
+// so that gotest and the gospec command can be used to run the specs.
+// This shouldn't require too much typing, because there will be
+// typically only one top-level spec per class/feature. The gospecgen
+// command can also generate this file.
 
 func TestAllSpecs(t *testing.T) {
 	r := gospec.NewRunner()
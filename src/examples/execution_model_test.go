@@ -44,6 +44,22 @@ func ExecutionModelSpec(c gospec.Context) {
 		                                    commonVariable == "x3y")
 	})
 
+	c.Specify("Tear down code can be deferred", func() {
+
+		// The code after the child specs is not executed if this spec panics.
+		// Deferred functions are executed after this spec and its child specs
+		// have finished, even if they fail or panic, in reverse order the same
+		// way as Go's defer statements.
+		tempFile := "created"
+		c.Defer(func() {
+			tempFile = "removed"
+		})
+
+		c.Specify("The deferred function has not yet been executed in the child specs", func() {
+			c.Expect(tempFile, Equals, "created")
+		})
+	})
+
 	c.Specify("You can nest", func() {
 		c.Specify("as many specs", func() {
 			c.Specify("as you wish.", func() {
@@ -10,15 +10,32 @@ import (
 )
 
 func TestAllSpecs(t *testing.T) {
+	nanospec.Run(t, ColorSpec)
 	nanospec.Run(t, ConcurrencySpec)
 	nanospec.Run(t, ContextSpec)
+	nanospec.Run(t, DeferSpec)
+	nanospec.Run(t, EventsSpec)
 	nanospec.Run(t, ExecutionModelSpec)
 	nanospec.Run(t, ExpectationsSpec)
+	nanospec.Run(t, FailFastSpec)
+	nanospec.Run(t, FailuresFileSpec)
+	nanospec.Run(t, FilterSpec)
+	nanospec.Run(t, FixtureSpec)
+	nanospec.Run(t, FocusSpec)
 	nanospec.Run(t, FuncNameSpec)
+	nanospec.Run(t, GoTestJSONSpec)
+	nanospec.Run(t, JUnitSpec)
 	nanospec.Run(t, LocationSpec)
 	nanospec.Run(t, MatcherMessagesSpec)
 	nanospec.Run(t, MatchersSpec)
 	nanospec.Run(t, PrinterSpec)
 	nanospec.Run(t, RecoverSpec)
 	nanospec.Run(t, ResultsSpec)
+	nanospec.Run(t, ResultsFileSpec)
+	nanospec.Run(t, RetrySpec)
+	nanospec.Run(t, ShardSpec)
+	nanospec.Run(t, SubtestsSpec)
+	nanospec.Run(t, TapFormatSpec)
+	nanospec.Run(t, TimeoutSpec)
+	nanospec.Run(t, TimingSpec)
 }
@@ -0,0 +1,42 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// ANSI escape codes for the terminal colors.
+const (
+	red        = "\x1b[31m"
+	green      = "\x1b[32m"
+	resetColor = "\x1b[0m"
+)
+
+// Tells whether the output should be colored. The mode is "always",
+// "never" or "auto", which colors the output if it is a terminal and the
+// NO_COLOR environment variable is not set, as explained at no-color.org.
+func UseColors(mode string, out io.Writer) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		return os.Getenv("NO_COLOR") == "" && isTerminal(out), nil
+	}
+	return false, fmt.Errorf("unknown color mode: %v", mode)
+}
+
+func isTerminal(out io.Writer) bool {
+	file, ok := out.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
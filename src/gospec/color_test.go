@@ -0,0 +1,74 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"bytes"
+	"github.com/orfjackal/nanospec.go/src/nanospec"
+	"os"
+	"strings"
+)
+
+func ColorSpec(c nanospec.Context) {
+	out := new(bytes.Buffer)
+
+	c.Specify("The colors can be forced on and off", func() {
+		colors, err := UseColors("always", out)
+		c.Expect(err).Equals(nil)
+		c.Expect(colors).IsTrue()
+		colors, err = UseColors("never", out)
+		c.Expect(err).Equals(nil)
+		c.Expect(colors).IsFalse()
+	})
+
+	c.Specify("By default the output is colored only on a terminal", func() {
+		colors, _ := UseColors("auto", out)
+		c.Expect(colors).IsFalse()
+
+		r, w, err := os.Pipe()
+		c.Expect(err).Equals(nil)
+		defer r.Close()
+		defer w.Close()
+		colors, _ = UseColors("auto", w)
+		c.Expect(colors).IsFalse()
+	})
+
+	c.Specify("An unknown color mode is an error", func() {
+		_, err := UseColors("sometimes", out)
+		c.Expect(err != nil).IsTrue()
+	})
+
+	c.Specify("With colors", func() {
+		format := ColorPrintFormat(out)
+
+		c.Specify("failures are red", func() {
+			failure := newError(ExpectFailed, "equals “2”", "1", []*Location{})
+			format.PrintFailing(0, "Failing", []*Error{failure})
+			c.Expect(out.String()).Equals("- Failing \x1b[31m[FAIL]\x1b[0m\n\n" +
+				"\x1b[31m*** Expected: equals “2”\x1b[0m\n" +
+				"\x1b[31m         got: “1”\x1b[0m\n" +
+				"\n\n")
+		})
+		c.Specify("passing specs are not colored", func() {
+			format.PrintPassing(1, "Passing")
+			c.Expect(out.String()).Equals("  - Passing\n")
+		})
+		c.Specify("the summary is red if some specs failed", func() {
+			format.PrintSummary(Summary{PassCount: 1, FailCount: 1})
+			c.Expect(out.String()).Equals("\n\x1b[31m2 specs, 1 failures\x1b[0m\n")
+		})
+		c.Specify("the summary is green if no specs failed", func() {
+			format.PrintSummary(Summary{PassCount: 1})
+			c.Expect(out.String()).Equals("\n\x1b[32m1 specs, 0 failures\x1b[0m\n")
+		})
+	})
+
+	c.Specify("Without colors, there are no escape codes", func() {
+		format := DefaultPrintFormat(out)
+		format.PrintFailing(0, "Failing", someError)
+		format.PrintSummary(Summary{FailCount: 1})
+		c.Expect(strings.Contains(out.String(), "\x1b")).IsFalse()
+	})
+}
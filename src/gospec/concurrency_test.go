@@ -7,6 +7,7 @@ package gospec
 import (
 	"math"
 	"github.com/orfjackal/nanospec.go/src/nanospec"
+	"sync"
 	"time"
 )
 
@@ -18,7 +19,17 @@ const (
 )
 
 func ConcurrencySpec(c nanospec.Context) {
+	c.Specify("Specs are executed in parallel", func() {
+		ParallelExecutionSpec(c)
+	})
+	c.Specify("The number of specs executed at a time is limited by the parallelism", func() {
+		LimitedParallelismSpec(c)
+	})
+}
+
+func ParallelExecutionSpec(c nanospec.Context) {
 	r := NewRunner()
+	r.SetParallelism(4)
 	r.AddSpec(VerySlowDummySpec)
 
 	start := time.Now()
@@ -58,3 +69,53 @@ func VerySlowDummySpec(c Context) {
 		})
 	})
 }
+
+func LimitedParallelismSpec(c nanospec.Context) {
+	resetConcurrencySpy()
+	r := NewRunner()
+	r.SetParallelism(2)
+	r.AddSpec(ConcurrencyCountingDummySpec)
+	r.Run()
+
+	c.Expect(maxConcurrentSpecs).Equals(2)
+
+	runCounts := countSpecNames(r.executed)
+	c.Expect(runCounts["Child A"]).Equals(1)
+	c.Expect(runCounts["Child B"]).Equals(1)
+	c.Expect(runCounts["Child C"]).Equals(1)
+	c.Expect(runCounts["Child D"]).Equals(1)
+}
+
+var (
+	concurrencySpyMutex = new(sync.Mutex)
+	concurrentSpecs     = 0
+	maxConcurrentSpecs  = 0
+)
+
+func resetConcurrencySpy() {
+	concurrentSpecs = 0
+	maxConcurrentSpecs = 0
+}
+
+func ConcurrencyCountingDummySpec(c Context) {
+	concurrencySpyMutex.Lock()
+	concurrentSpecs++
+	if concurrentSpecs > maxConcurrentSpecs {
+		maxConcurrentSpecs = concurrentSpecs
+	}
+	concurrencySpyMutex.Unlock()
+
+	time.Sleep(DELAY)
+	c.Specify("Child A", func() {
+	})
+	c.Specify("Child B", func() {
+	})
+	c.Specify("Child C", func() {
+	})
+	c.Specify("Child D", func() {
+	})
+
+	concurrencySpyMutex.Lock()
+	concurrentSpecs--
+	concurrencySpyMutex.Unlock()
+}
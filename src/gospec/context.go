@@ -6,6 +6,9 @@ package gospec
 
 import (
 	"container/list"
+	"fmt"
+	"runtime"
+	"time"
 )
 
 // Context controls the execution of the current spec. Child specs can be
@@ -18,6 +21,24 @@ type Context interface {
 	// specification as code.
 	Specify(name string, closure func())
 
+	// Same as Specify, but focuses on this spec. When some specs are focused,
+	// the other specs are reported as skipped without executing them, unless
+	// they are the parents or children of a focused spec. The children of
+	// the root specs are declared before executing any of them, but a spec
+	// which is nested deeper is found to be focused only when its parent is
+	// executed, so the specs which were executed before that, such as its
+	// earlier siblings, are reported normally. Meant to be used only
+	// temporarily while working on some specs.
+	FSpecify(name string, closure func())
+
+	// Same as Specify, but disables this spec. The spec is reported as
+	// skipped and its closure is not executed.
+	XSpecify(name string, closure func())
+
+	// Creates a child spec for behaviour which has not yet been specified.
+	// The spec is reported as pending.
+	Pending(name string)
+
 	// Makes an expectation. For example:
 	//    c.Expect(theAnswer, Equals, 42)
 	//    c.Expect(theAnswer, Not(Equals), 666)
@@ -27,6 +48,35 @@ type Context interface {
 	// Makes an assumption. Otherwise the same as an expectation,
 	// but on failure will not continue executing the child specs.
 	Assume(actual interface{}, matcher Matcher, expected ...interface{})
+
+	// Sets the timeout for the currently executing spec and its child specs,
+	// overriding the Runner's default timeout. A spec times out if it runs for
+	// longer than the timeout, measured from when the spec started and
+	// including the time spent in its children. Zero means no timeout.
+	SetTimeout(timeout time.Duration)
+
+	// Skips the currently executing spec and its child specs, for example
+	// when something which the spec requires is not available. The rest of
+	// the spec's closure is not executed, and the spec is reported as
+	// skipped with the reason, instead of as failed.
+	Skip(reason string)
+
+	// Same as Skip, but formats the reason like fmt.Sprintf.
+	Skipf(format string, args ...interface{})
+
+	// Registers a function to be executed after the currently executing
+	// spec and its child specs have finished, for example to tear down
+	// changes to the file system. The deferred functions are executed in
+	// reverse order, even if the spec fails, panics or times out. After a
+	// timeout they are executed while the timed out spec may still be
+	// running, because it cannot be stopped.
+	Defer(f func())
+
+	// Sets how many times the currently executing spec and its child specs
+	// are retried if they fail, overriding the Runner's default. A spec which
+	// passes when it is retried is reported as flaky. Meant to be used only
+	// for specs which are known to fail intermittently.
+	Retry(times int)
 }
 
 type taskContext struct {
@@ -34,6 +84,18 @@ type taskContext struct {
 	currentSpec    *specRun
 	executedSpecs  *list.List
 	postponedSpecs *list.List
+	defaultTimeout time.Duration
+	defaultRetries int
+	attempt        int  // how many times the retried spec has been retried
+	retriedPath    path // of the spec which is retried, on the target path
+	watchdog       *watchdog
+	timedOutPaths  []path
+	filter         *nameFilter
+	focus          *focusedSpecs
+	declareOnly    bool     // whether the children of the target spec are only declared, not executed
+	targetNames    []string // names of the specs on the target path, when rerunning failed specs
+	targetChanged  bool
+	canFallBack    bool
 }
 
 func newInitialContext() *taskContext {
@@ -46,25 +108,182 @@ func newExplicitContext(targetPath path) *taskContext {
 	c.currentSpec = nil
 	c.executedSpecs = list.New()
 	c.postponedSpecs = list.New()
+	c.defaultTimeout = 0
+	c.watchdog = newWatchdog()
+	c.timedOutPaths = []path{}
+	c.filter = newNameFilter()
+	c.focus = newFocusedSpecs()
+	return c
+}
+
+// Returns a context for executing the rest of the specs which were not
+// reached because of a timeout. The specs which timed out will be skipped.
+func (c *taskContext) continuationAfterTimeout() *taskContext {
+	if !c.watchdog.timedOut {
+		return nil
+	}
+	next := newExplicitContext(c.targetPath)
+	next.filter = c.filter
+	next.focus = c.focus
+	next.timedOutPaths = append(next.timedOutPaths, c.timedOutPaths...)
+	next.timedOutPaths = append(next.timedOutPaths, c.watchdog.spec.path)
+	next.targetNames = c.targetNames
+	next.canFallBack = c.canFallBack
+	next.attempt = c.attempt
+	next.retriedPath = c.retriedPath
+	return next
+}
+
+// Returns a context for retrying a spec after it failed. When the spec is
+// a parent of the target spec, only the path to the target spec is retried,
+// because the other children of the spec already passed on earlier runs.
+func newRetryContext(targetPath path, retriedPath path, attempt int) *taskContext {
+	c := newExplicitContext(targetPath)
+	c.retriedPath = retriedPath
+	c.attempt = attempt
+	return c
+}
+
+// Returns a context for rerunning a failed spec which has the names of the
+// specs on its path, in addition to the indexes of the path.
+func newRerunContext(targetPath path, targetNames []string) *taskContext {
+	c := newExplicitContext(targetPath)
+	c.targetNames = targetNames
+	c.canFallBack = true
 	return c
 }
 
+// Returns a context for finding the failed spec by its names, if the specs
+// have been changed since the spec failed, so that the spec is no more at
+// the same path.
+func (c *taskContext) nameMatchingFallback() *taskContext {
+	if !c.canFallBack || !c.targetChanged {
+		return nil
+	}
+	next := newInitialContext()
+	next.targetNames = c.targetNames
+	return next
+}
+
+// Executes the root spec in its own goroutine, so that the task
+// can be abandoned if a spec is blocked and times out. Returns a channel
+// which is closed when the goroutine of a timed out task has stopped,
+// or nil if the task did not time out.
+func (c *taskContext) run(name string, closure func()) (stillRunning <-chan bool) {
+	done := make(chan bool)
+	go func() {
+		// closed also when the goroutine is stopped after a timeout
+		defer close(done)
+		c.watchdog.watchCurrentGoroutine()
+		c.Specify(name, closure)
+	}()
+	c.watchdog.waitUntilFinished(done)
+	if !c.watchdog.timedOut {
+		return nil
+	}
+	c.recordDurationsAfterTimeout()
+	c.runDeferredAfterTimeout()
+	return done
+}
+
+// The specs which were executing when the task timed out will never finish,
+// so their deferred functions are executed here, starting from the spec
+// which timed out, even though the goroutine of the task may still be
+// running. It will stop when it calls the Context the next time.
+func (c *taskContext) runDeferredAfterTimeout() {
+	c.watchdog.mutex.Lock()
+	specs := []*specRun{}
+	deferred := [][]func(){}
+	for spec := c.watchdog.spec; spec != nil; spec = spec.parent {
+		specs = append(specs, spec)
+		deferred = append(deferred, spec.takeDeferred())
+	}
+	c.watchdog.mutex.Unlock()
+	for i, spec := range specs {
+		runDeferred(deferred[i], spec)
+	}
+}
+
+// The specs which were executing when the task timed out will never finish,
+// so their durations are until the timeout.
+func (c *taskContext) recordDurationsAfterTimeout() {
+	c.watchdog.mutex.Lock()
+	defer c.watchdog.mutex.Unlock()
+	for spec := c.watchdog.spec; spec != nil; spec = spec.parent {
+		if spec.duration == 0 && !spec.started.IsZero() {
+			spec.duration = time.Since(spec.started)
+		}
+	}
+}
+
+// All changes to the state of the task must be synchronized with the
+// watchdog, because it may time out the task while a spec is still running.
+// After that the goroutine of the timed out task is not allowed to proceed.
+func (c *taskContext) synchronized(action func()) {
+	c.watchdog.mutex.Lock()
+	if c.watchdog.timedOut {
+		c.watchdog.mutex.Unlock()
+		runtime.Goexit()
+	}
+	defer c.watchdog.mutex.Unlock()
+	action()
+}
+
 func (c *taskContext) Specify(name string, closure func()) {
-	c.enterSpec(name, closure)
+	c.specify(name, closure, func(spec *specRun) {})
+}
+
+func (c *taskContext) FSpecify(name string, closure func()) {
+	c.specify(name, closure, func(spec *specRun) {
+		spec.focused = true
+		c.focus.add(spec)
+	})
+}
+
+func (c *taskContext) XSpecify(name string, closure func()) {
+	c.specify(name, closure, func(spec *specRun) { spec.status = Skipped })
+}
+
+func (c *taskContext) Pending(name string) {
+	c.specify(name, func() {}, func(spec *specRun) { spec.status = Pending })
+}
+
+func (c *taskContext) specify(name string, closure func(), mark func(*specRun)) {
+	c.synchronized(func() {
+		c.enterSpec(name, closure)
+		mark(c.currentSpec)
+	})
 	c.processCurrentSpec()
-	c.exitSpec()
+	c.synchronized(c.exitSpec)
 }
 
 func (c *taskContext) enterSpec(name string, closure func()) {
 	spec := newSpecRun(name, closure, c.currentSpec, c.targetPath)
+	if spec.parent == nil {
+		spec.timeout = c.defaultTimeout
+		spec.retries = c.defaultRetries
+	}
+	if spec.isOnTargetPath() && !c.matchesTargetNames(spec) {
+		c.targetChanged = true
+	}
+	spec.searched = !c.filter.selects(spec)
 	c.currentSpec = spec
 }
 
 func (c *taskContext) processCurrentSpec() {
 	spec := c.currentSpec
 	switch {
+	case c.shouldIgnore(spec):
+		// not executed nor reported
+	case c.shouldAbort(spec):
+		c.abort(spec)
+	case c.shouldReportWithoutExecuting(spec):
+		c.reportWithoutExecuting(spec)
+	case c.shouldSkipUnfocused(spec):
+		c.skipUnfocused(spec)
 	case c.shouldExecute(spec):
 		c.execute(spec)
+		c.checkTargetWasDeclared(spec)
 	case c.shouldPostpone(spec):
 		c.postpone(spec)
 	}
@@ -78,36 +297,210 @@ func (c *taskContext) shouldExecute(spec *specRun) bool {
 	if spec.parent != nil && spec.parent.hasFatalErrors {
 		return false
 	}
-	return spec.isOnTargetPath() || (spec.isUnseen() && spec.isFirstChild())
+	return spec.isOnTargetPath() || (spec.isUnseen() && !spec.hasExecutedSiblings() && !c.declareOnly)
+}
+
+// When an assumption of the parent fails, its children are reported
+// as aborted, so that it's known that they were not executed.
+func (c *taskContext) shouldAbort(spec *specRun) bool {
+	return spec.parent != nil && spec.parent.hasFatalErrors &&
+		(spec.isOnTargetPath() || spec.isUnseen())
+}
+
+// Disabled specs are reported on the same run as they are first seen,
+// so they don't need to be postponed, which saves executing their parents.
+func (c *taskContext) shouldReportWithoutExecuting(spec *specRun) bool {
+	return spec.isDisabled() && (spec.isOnTargetPath() || spec.isUnseen())
+}
+
+// When some specs are focused, the specs which are not related to them are
+// reported as skipped without executing them. The parents of the target spec
+// were already executed on an earlier run, so they are not skipped.
+func (c *taskContext) shouldSkipUnfocused(spec *specRun) bool {
+	return (spec.path.isEqual(c.targetPath) || spec.isUnseen()) && c.focus.isUnrelated(spec)
 }
 
 func (c *taskContext) shouldPostpone(spec *specRun) bool {
-	return spec.isUnseen() && !spec.isFirstChild()
+	return spec.isUnseen() && (spec.hasExecutedSiblings() || c.declareOnly)
+}
+
+func (c *taskContext) shouldIgnore(spec *specRun) bool {
+	return c.filter.isSkipped(spec) || c.hasTimedOut(spec) || !c.matchesTargetNames(spec)
+}
+
+func (c *taskContext) matchesTargetNames(spec *specRun) bool {
+	level := len(spec.path)
+	return level >= len(c.targetNames) || spec.name == c.targetNames[level]
+}
+
+// If a spec on the target path finishes successfully without declaring the
+// next spec on the target path, the specs have been changed.
+func (c *taskContext) checkTargetWasDeclared(spec *specRun) {
+	c.synchronized(func() {
+		level := len(spec.path)
+		if level < len(c.targetPath) && spec.errors.Len() == 0 && spec.numberOfChildren <= c.targetPath[level] {
+			c.targetChanged = true
+		}
+	})
+}
+
+func (c *taskContext) hasTimedOut(spec *specRun) bool {
+	for _, timedOut := range c.timedOutPaths {
+		if spec.path.isEqual(timedOut) {
+			return true
+		}
+	}
+	return false
 }
 
 func (c *taskContext) execute(spec *specRun) {
-	c.executedSpecs.PushBack(spec)
-	spec.execute()
+	c.synchronized(func() {
+		c.executedSpecs.PushBack(spec)
+		spec.markExecuted()
+		spec.started = time.Now()
+		c.watchdog.watch(spec)
+		if c.attempt > 0 && spec.path.isEqual(c.retriedPath) {
+			// unless it fails again
+			spec.status = Flaky
+		}
+	})
+	spec.execute(c.errorLogger(spec))
+	c.runDeferred(spec)
+	c.synchronized(func() {
+		spec.duration = time.Since(spec.started)
+		c.watchdog.watch(spec.parent)
+	})
+}
+
+// The specs which were executed only for finding the specs which match the
+// focus pattern are reported only as the parents of the matching specs.
+func (c *taskContext) reportedSpecs() []*specRun {
+	executed := asSpecArray(c.executedSpecs)
+	reported := make([]*specRun, 0, len(executed))
+	for _, spec := range executed {
+		if !spec.searched || hasSelectedChildren(spec, executed) {
+			reported = append(reported, spec)
+		}
+	}
+	return reported
+}
+
+func hasSelectedChildren(spec *specRun, executed []*specRun) bool {
+	for _, other := range executed {
+		if !other.searched && spec.path.isOn(other.path) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *taskContext) runDeferred(spec *specRun) {
+	var deferred []func()
+	c.synchronized(func() {
+		deferred = spec.takeDeferred()
+	})
+	runDeferred(deferred, c.errorLogger(spec))
+}
+
+func (c *taskContext) reportWithoutExecuting(spec *specRun) {
+	c.synchronized(func() {
+		c.executedSpecs.PushBack(spec)
+	})
+}
+
+func (c *taskContext) skipUnfocused(spec *specRun) {
+	c.synchronized(func() {
+		spec.status = Skipped
+		c.executedSpecs.PushBack(spec)
+	})
+}
+
+func (c *taskContext) abort(spec *specRun) {
+	c.synchronized(func() {
+		spec.status = Aborted
+		c.executedSpecs.PushBack(spec)
+	})
 }
 
 func (c *taskContext) postpone(spec *specRun) {
-	c.postponedSpecs.PushBack(spec)
+	c.synchronized(func() {
+		c.postponedSpecs.PushBack(spec)
+	})
 }
 
 func (c *taskContext) Expect(actual interface{}, matcher Matcher, expected ...interface{}) {
 	location := callerLocation()
-	logger := expectationLogger{c.currentSpec}
+	logger := expectationLogger{c.errorLogger(c.currentSpec)}
 	m := newMatcherAdapter(location, logger, ExpectFailed)
 	m.Expect(actual, matcher, expected...)
 }
 
 func (c *taskContext) Assume(actual interface{}, matcher Matcher, expected ...interface{}) {
 	location := callerLocation()
-	logger := assumptionLogger{c.currentSpec}
+	logger := assumptionLogger{c.errorLogger(c.currentSpec)}
 	m := newMatcherAdapter(location, logger, AssumeFailed)
 	m.Expect(actual, matcher, expected...)
 }
 
+func (c *taskContext) SetTimeout(timeout time.Duration) {
+	c.synchronized(func() {
+		c.currentSpec.timeout = timeout
+		c.watchdog.watch(c.currentSpec)
+	})
+}
+
+func (c *taskContext) Defer(f func()) {
+	c.synchronized(func() {
+		c.currentSpec.deferred = append(c.currentSpec.deferred, f)
+	})
+}
+
+func (c *taskContext) Retry(times int) {
+	c.synchronized(func() {
+		c.currentSpec.retries = times
+	})
+}
+
+func (c *taskContext) Skip(reason string) {
+	c.skip(reason, callerLocation())
+}
+
+func (c *taskContext) Skipf(format string, args ...interface{}) {
+	c.skip(fmt.Sprintf(format, args...), callerLocation())
+}
+
+func (c *taskContext) skip(reason string, location *Location) {
+	e := newError(SkipRequested, reason, "", toStackTrace(location))
+	c.errorLogger(c.currentSpec).AddError(e)
+	panic(skipRequest{})
+}
+
+func (c *taskContext) failCurrentSpec(message string) {
+	e := newError(OtherError, message, "", []*Location{})
+	c.errorLogger(c.currentSpec).AddFatalError(e)
+}
+
+func (c *taskContext) errorLogger(spec *specRun) ratedErrorLogger {
+	return synchronizedLogger{c, spec}
+}
+
+type synchronizedLogger struct {
+	c   *taskContext
+	log ratedErrorLogger
+}
+
+func (this synchronizedLogger) AddError(e *Error) {
+	this.c.synchronized(func() {
+		this.log.AddError(e)
+	})
+}
+
+func (this synchronizedLogger) AddFatalError(e *Error) {
+	this.c.synchronized(func() {
+		this.log.AddFatalError(e)
+	})
+}
+
 type expectationLogger struct {
 	log ratedErrorLogger
 }
@@ -0,0 +1,106 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"github.com/orfjackal/nanospec.go/src/nanospec"
+)
+
+func DeferSpec(c nanospec.Context) {
+
+	c.Specify("Deferred functions are executed after the spec and its children, in reverse order", func() {
+		resetTestSpy()
+		runSpec(func(c Context) {
+			testSpy += "root"
+			c.Defer(func() { testSpy += ",defer 1" })
+			c.Defer(func() { testSpy += ",defer 2" })
+			c.Specify("Child", func() {
+				testSpy += ",child"
+			})
+		})
+		c.Expect(testSpy).Equals("root,defer 2,defer 1" + "root,child,defer 2,defer 1")
+	})
+
+	c.Specify("Deferred functions are executed on every run of the spec", func() {
+		resetTestSpy()
+		runSpec(func(c Context) {
+			c.Defer(func() { testSpy += "defer," })
+			c.Specify("Child A", func() {})
+			c.Specify("Child B", func() {})
+		})
+		c.Expect(testSpy).Equals("defer,defer,defer,")
+	})
+
+	c.Specify("Deferred functions are executed even when the spec panics", func() {
+		resetTestSpy()
+		results := runSpec(func(c Context) {
+			c.Defer(func() { testSpy += "defer" })
+			panic("boom")
+		})
+		c.Expect(testSpy).Equals("defer")
+		c.Expect(results.FailCount()).Equals(1)
+	})
+
+	c.Specify("Deferred functions are executed even when an assumption fails", func() {
+		resetTestSpy()
+		runSpec(func(c Context) {
+			c.Defer(func() { testSpy += "defer" })
+			c.Assume(1, Equals, 2)
+			c.Specify("Child", func() {})
+		})
+		c.Expect(testSpy).Equals("defer")
+	})
+
+	c.Specify("Deferred functions are executed even when the spec times out", func() {
+		resetTestSpy()
+		r := NewRunner()
+		r.SetTimeout(TIMEOUT)
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			c.Defer(func() { testSpy += "root defer," })
+			c.Specify("Blocked", func() {
+				c.Defer(func() { testSpy += "child defer," })
+				blockForever()
+			})
+		})
+		r.Run()
+		c.Expect(testSpy).Equals("root defer," + "child defer,root defer," + "root defer,")
+		c.Expect(r.Results().FailCount()).Equals(1)
+	})
+
+	c.Specify("Deferred functions of a child spec are executed before the rest of its parent", func() {
+		resetTestSpy()
+		runSpec(func(c Context) {
+			testSpy += "root"
+			c.Defer(func() { testSpy += ",parent defer" })
+			c.Specify("Child", func() {
+				c.Defer(func() { testSpy += ",child defer" })
+				panic("boom")
+			})
+			testSpy += ",after child"
+		})
+		c.Expect(testSpy).Equals("root,after child,parent defer" + "root,child defer,after child,parent defer")
+	})
+
+	c.Specify("When a deferred function panics", func() {
+		resetTestSpy()
+		results := runSpec(func(c Context) {
+			c.Defer(func() { testSpy += "defer 1" })
+			c.Defer(func() { panic("teardown failed") })
+		})
+
+		c.Specify("then the other deferred functions are still executed", func() {
+			c.Expect(testSpy).Equals("defer 1")
+		})
+		c.Specify("then the spec fails", func() {
+			c.Expect(results).Matches(ReportIs(`
+- RootSpec [FAIL]
+*** panic: teardown failed
+    at defer_test.go
+
+1 specs, 1 failures
+`))
+		})
+	})
+}
@@ -12,8 +12,26 @@ const (
 	ExpectFailed ErrorType = iota
 	AssumeFailed
 	OtherError
+	TimedOut
+	SkipRequested
 )
 
+func (this ErrorType) String() string {
+	switch this {
+	case ExpectFailed:
+		return "ExpectFailed"
+	case AssumeFailed:
+		return "AssumeFailed"
+	case OtherError:
+		return "OtherError"
+	case TimedOut:
+		return "TimedOut"
+	case SkipRequested:
+		return "SkipRequested"
+	}
+	return "unknown"
+}
+
 type Error struct {
 	Type       ErrorType
 	Message    string
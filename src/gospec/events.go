@@ -0,0 +1,117 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// The events are streamed as newline-delimited JSON, one object per line,
+// so that long runs can be followed while the specs are executed. The
+// "start" event is written first, then a "spec" event whenever a spec has
+// been executed, and the "end" event when all specs have been executed.
+// A parent spec is executed again for each of its children, so the same
+// spec may be reported again, if its status or errors were changed by a
+// later run. The last event of a spec has the same results as the results
+// file.
+type startEvent struct {
+	Event   string `json:"event"`
+	Version int    `json:"version"`
+}
+
+type specEvent struct {
+	Event    string        `json:"event"`
+	Names    []string      `json:"names"` // of the root spec, its nested specs and the spec itself
+	Path     []int         `json:"path"`
+	Status   string        `json:"status"`
+	Duration float64       `json:"duration"` // in seconds, of all the runs so far
+	Errors   []*savedError `json:"errors,omitempty"`
+}
+
+type endEvent struct {
+	Event    string         `json:"event"`
+	Counts   map[string]int `json:"counts"` // number of specs, by status
+	Shuffled bool           `json:"shuffled"`
+	Seed     int64          `json:"seed"`
+}
+
+// The results of the specs are collected also here, because a spec may be
+// executed many times, and only the changes are reported. Streaming stops
+// at the first write error, so that it does not fail the specs. A nil
+// stream ignores all events.
+type eventStream struct {
+	encoder  *json.Encoder
+	results  *ResultCollector
+	reported map[*specResult]string
+	err      error
+}
+
+func newEventStream(out io.Writer) *eventStream {
+	return &eventStream{
+		encoder:  json.NewEncoder(out),
+		results:  NewResultCollector(),
+		reported: make(map[*specResult]string),
+	}
+}
+
+func (s *eventStream) start() {
+	if s == nil {
+		return
+	}
+	s.write(&startEvent{"start", resultsFormatVersion})
+}
+
+func (s *eventStream) specExecuted(spec *specRun) {
+	if s == nil {
+		return
+	}
+	root := s.results.getOrCreateRoot(spec)
+	root.update(spec)
+	result := root
+	for result != nil && !result.path.isEqual(spec.path) {
+		result = result.findChildOnPath(spec.path)
+	}
+	if result == nil {
+		return
+	}
+	status := result.status().String()
+	state := fmt.Sprint(status, result.errors.Len())
+	if s.reported[result] == state {
+		return
+	}
+	s.reported[result] = state
+	event := &specEvent{"spec", spec.names(), spec.path, status, result.duration.Seconds(), nil}
+	for e := result.errors.Front(); e != nil; e = e.Next() {
+		event.Errors = append(event.Errors, saveError(e.Value.(*Error)))
+	}
+	s.write(event)
+}
+
+func (s *eventStream) end(shuffled bool, seed int64) {
+	if s == nil {
+		return
+	}
+	counts := make(map[string]int)
+	for status, count := range s.results.specCounts() {
+		counts[status.String()] = count
+	}
+	s.write(&endEvent{"end", counts, shuffled, seed})
+}
+
+func (s *eventStream) write(event interface{}) {
+	if s.err == nil {
+		s.err = s.encoder.Encode(event)
+	}
+}
+
+// Returns the names of the spec and its parents, beginning with the root spec.
+func (spec *specRun) names() []string {
+	if spec.parent == nil {
+		return []string{spec.name}
+	}
+	return append(spec.parent.names(), spec.name)
+}
@@ -0,0 +1,131 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/orfjackal/nanospec.go/src/nanospec"
+	"strings"
+)
+
+type streamedEvent struct {
+	Event   string         `json:"event"`
+	Version int            `json:"version"`
+	Names   []string       `json:"names"`
+	Path    []int          `json:"path"`
+	Status  string         `json:"status"`
+	Errors  []*savedError  `json:"errors"`
+	Counts  map[string]int `json:"counts"`
+}
+
+func streamEvents(spec func(Context)) []*streamedEvent {
+	out := new(bytes.Buffer)
+	r := NewRunner()
+	r.AddNamedSpec("RootSpec", spec)
+	r.StreamEvents(out)
+	r.RunSerially()
+	events := []*streamedEvent{}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		event := &streamedEvent{}
+		if err := json.Unmarshal([]byte(line), event); err != nil {
+			panic(err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func eventNames(events []*streamedEvent) []string {
+	names := []string{}
+	for _, event := range events {
+		if event.Event == "spec" {
+			names = append(names, strings.Join(event.Names, " / ")+" "+event.Status)
+		} else {
+			names = append(names, event.Event)
+		}
+	}
+	return names
+}
+
+func EventsSpec(c nanospec.Context) {
+
+	c.Specify("The events are streamed in the order of execution, between the start and end events", func() {
+		events := streamEvents(func(c Context) {
+			c.Specify("Passing", func() {})
+			c.Specify("Parent", func() {
+				c.Specify("Failing", func() {
+					c.Expect(1, Equals, 2)
+				})
+			})
+		})
+		c.Expect(eventNames(events)).Equals([]string{
+			"start",
+			"RootSpec passed",
+			"RootSpec / Passing passed",
+			"RootSpec / Parent passed",
+			"RootSpec / Parent / Failing failed",
+			"end",
+		})
+	})
+
+	c.Specify("The start event has the version of the format", func() {
+		events := streamEvents(func(c Context) {})
+		c.Expect(events[0].Version).Equals(resultsFormatVersion)
+	})
+
+	c.Specify("The spec events have the path and errors of the spec", func() {
+		events := streamEvents(func(c Context) {
+			c.Specify("Passing", func() {})
+			c.Specify("Failing", func() {
+				c.Expect(1, Equals, 2)
+			})
+		})
+		failing := events[3]
+		c.Expect(failing.Path).Equals([]int{1})
+		c.Expect(len(failing.Errors)).Equals(1)
+		c.Expect(failing.Errors[0].Type).Equals("ExpectFailed")
+		c.Expect(failing.Errors[0].Message).Equals("equals “2”")
+		c.Expect(failing.Errors[0].Actual).Equals("1")
+		c.Expect(strings.HasSuffix(failing.Errors[0].StackTrace[0].File, "events_test.go")).IsTrue()
+	})
+
+	c.Specify("A parent spec is reported again only if its results change on a later run", func() {
+		runs := 0
+		events := streamEvents(func(c Context) {
+			c.Specify("Parent", func() {
+				runs++
+				if runs == 2 {
+					c.Expect(1, Equals, 2)
+				}
+				c.Specify("Child 1", func() {})
+				c.Specify("Child 2", func() {})
+				c.Specify("Child 3", func() {})
+			})
+		})
+		c.Expect(eventNames(events)).Equals([]string{
+			"start",
+			"RootSpec passed",
+			"RootSpec / Parent passed",
+			"RootSpec / Parent / Child 1 passed",
+			"RootSpec / Parent failed",
+			"RootSpec / Parent / Child 2 passed",
+			"RootSpec / Parent / Child 3 passed",
+			"end",
+		})
+	})
+
+	c.Specify("The end event has the number of specs by status", func() {
+		events := streamEvents(func(c Context) {
+			c.Specify("Passing", func() {})
+			c.Specify("Failing", func() {
+				c.Expect(1, Equals, 2)
+			})
+			c.Pending("Pending")
+		})
+		end := events[len(events)-1]
+		c.Expect(end.Counts).Equals(map[string]int{"passed": 2, "failed": 1, "pending": 1})
+	})
+}
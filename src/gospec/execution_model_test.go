@@ -64,4 +64,60 @@ func ExecutionModelSpec(c nanospec.Context) {
 		c.Expect(runs[3]).Equals("root,b,bb")
 		c.Expect(runs[4]).Equals("root,b,bc")
 	})
+
+	c.Specify("When executed serially, the specs are executed one at a time in declaration order", func() {
+		resetTestSpy()
+		r := NewRunner()
+		r.AddSpec(DummySpecWithMultipleNestedChildren)
+		r.AddSpec(DummySpecWithTwoChildren)
+		r.RunSerially()
+
+		c.Expect(testSpy).Equals("" +
+			"root" +
+			"root" +
+			"root,a,aa" +
+			"root,a,ab" +
+			"root,b,ba" +
+			"root,b,bb" +
+			"root,b,bc" +
+			"root,a" +
+			"root,b")
+	})
+
+	c.Specify("When shuffled", func() {
+		run := func(shuffle bool, seed int64) string {
+			resetTestSpy()
+			r := NewRunner()
+			if shuffle {
+				r.Shuffle(seed)
+			}
+			r.AddSpec(DummySpecWithMultipleNestedChildren)
+			r.AddSpec(DummySpecWithTwoChildren)
+			r.RunSerially()
+			return testSpy
+		}
+		order := run(true, 42)
+
+		c.Specify("the order is different from the declaration order", func() {
+			c.Expect(order).NotEquals(run(false, 0))
+		})
+		c.Specify("different seeds produce different orders", func() {
+			c.Expect(run(true, 43)).NotEquals(order)
+		})
+		c.Specify("the same seed reproduces the same order", func() {
+			c.Expect(run(true, 42)).Equals(order)
+		})
+		c.Specify("all the specs are executed", func() {
+			c.Expect(len(order)).Equals(len("" +
+				"root" +
+				"root" +
+				"root,a,aa" +
+				"root,a,ab" +
+				"root,b,ba" +
+				"root,b,bb" +
+				"root,b,bc" +
+				"root,a" +
+				"root,b"))
+		})
+	})
 }
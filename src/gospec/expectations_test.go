@@ -40,16 +40,31 @@ func ExpectationsSpec(c nanospec.Context) {
 	})
 
 	c.Specify("When a spec has failing assumptions", func() {
+		childRuns := 0
 		results := runSpec(func(c Context) {
 			c.Assume(1, Equals, 2)
-			c.Specify("Child", func() {})
+			c.Specify("Child", func() {
+				childRuns++
+			})
 		})
 
 		c.Specify("then the spec fails", func() {
 			c.Expect(results.FailCount()).Equals(1)
 		})
 		c.Specify("then its children are NOT executed", func() {
-			c.Expect(results.TotalCount()).Equals(1)
+			c.Expect(childRuns).Equals(0)
+		})
+		c.Specify("then its children are reported as aborted", func() {
+			c.Expect(results.AbortCount()).Equals(1)
+			c.Expect(results).Matches(ReportIs(`
+- RootSpec [FAIL]
+*** Assumed: equals “2”
+        got: “1”
+    at expectations_test.go
+  - Child [ABORTED]
+
+2 specs, 1 failures, 1 aborted
+`))
 		})
 	})
 
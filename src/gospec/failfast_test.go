@@ -0,0 +1,125 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"github.com/orfjackal/nanospec.go/src/nanospec"
+	"time"
+)
+
+func FailFastSpec(c nanospec.Context) {
+	r := NewRunner()
+	r.FailFast()
+
+	c.Specify("When executing the specs one at a time", func() {
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			c.Specify("Passing", func() {})
+			c.Specify("Failing", func() {
+				c.Expect(1, Equals, 2)
+			})
+			c.Specify("Not executed", func() {})
+		})
+		r.AddNamedSpec("OtherSpec", DummySpecWithNoChildren)
+		r.RunSerially()
+		results := r.Results()
+
+		c.Specify("the specs after the first failure are not executed", func() {
+			c.Expect(results).Matches(ReportIs(`
+- OtherSpec
+- RootSpec
+  - Passing
+  - Failing [FAIL]
+*** Expected: equals “2”
+         got: “1”
+    at failfast_test.go
+  - Not executed [NOT RUN]
+
+5 specs, 1 failures, 1 not run
+`))
+		})
+		c.Specify("the specs which were not executed do not pass", func() {
+			c.Expect(results.PassCount()).Equals(3)
+			c.Expect(results.NotRunCount()).Equals(1)
+		})
+	})
+
+	c.Specify("When rerunning the failed specs, the failed specs after the first failure are not executed", func() {
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			c.Specify("A", func() {
+				c.Expect(1, Equals, 2)
+			})
+			c.Specify("Passing", func() {})
+			c.Specify("B", func() {
+				c.Specify("C", func() {
+					c.Expect(1, Equals, 2)
+				})
+			})
+		})
+		r.rerunFailed([]*failedSpec{
+			{[]string{"RootSpec", "A"}, []int{0}},
+			{[]string{"RootSpec", "B", "C"}, []int{2, 0}},
+		})
+		r.RunSerially()
+		results := r.Results()
+
+		c.Specify("they are reported as not run", func() {
+			c.Expect(results).Matches(ReportIs(`
+- RootSpec
+  - A [FAIL]
+*** Expected: equals “2”
+         got: “1”
+    at failfast_test.go
+  - B [NOT RUN]
+    - C [NOT RUN]
+
+4 specs, 1 failures, 2 not run
+`))
+		})
+		c.Specify("they are saved to be rerun again", func() {
+			failed := results.failedSpecs()
+			c.Expect(len(failed)).Equals(2)
+			c.Expect(failed[1].Names).Equals([]string{"RootSpec", "B", "C"})
+			c.Expect(failed[1].Path).Equals([]int{2, 0})
+		})
+	})
+
+	c.Specify("Skipping a spec does not stop the execution", func() {
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			c.Specify("Skipped", func() {
+				c.Skip("some reason")
+			})
+			c.Specify("Passing", func() {})
+		})
+		r.RunSerially()
+		c.Expect(r.Results().NotRunCount()).Equals(0)
+	})
+
+	c.Specify("When executing the specs in parallel, the specs which were already started are finished", func() {
+		r.SetParallelism(2)
+		r.AddNamedSpec("FailingSpec", func(c Context) {
+			c.Expect(1, Equals, 2)
+		})
+		r.AddNamedSpec("PassingSpec", func(c Context) {
+			time.Sleep(10 * time.Millisecond)
+		})
+		r.AddNamedSpec("NotExecutedSpec", DummySpecWithNoChildren)
+		r.Run()
+		results := r.Results()
+		c.Expect(results.FailCount()).Equals(1)
+		c.Expect(results.PassCount()).Equals(1)
+		c.Expect(results.NotRunCount()).Equals(1)
+	})
+
+	c.Specify("Without fail fast, all the specs are executed", func() {
+		r := NewRunner()
+		r.AddNamedSpec("FailingSpec", func(c Context) {
+			c.Expect(1, Equals, 2)
+		})
+		r.AddNamedSpec("PassingSpec", DummySpecWithNoChildren)
+		r.RunSerially()
+		c.Expect(r.Results().NotRunCount()).Equals(0)
+		c.Expect(r.Results().PassCount()).Equals(1)
+	})
+}
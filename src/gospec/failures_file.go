@@ -0,0 +1,87 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+)
+
+// The failed specs, and the specs which were not run because of fail fast,
+// are saved after every run, so that they can be rerun with
+// -gospec.rerun-failed. A failed spec is identified by its path, and
+// by the names of the specs on the path, beginning with the root spec, in
+// case the specs are changed so that the path no more leads to the spec.
+type failedSpec struct {
+	Names []string `json:"names"`
+	Path  []int    `json:"path"`
+}
+
+type savedFailures struct {
+	Failed []*failedSpec `json:"failed"`
+}
+
+// Returns the specs which failed or were not run. The children of a failed
+// spec are not included, because rerunning the spec reruns also its children.
+func (r *ResultCollector) failedSpecs() []*failedSpec {
+	failed := make([]*failedSpec, 0)
+	for root := range r.sortedRoots() {
+		failed = root.appendFailedSpecs(failed, []string{})
+	}
+	return failed
+}
+
+func (this *specResult) appendFailedSpecs(failed []*failedSpec, parentNames []string) []*failedSpec {
+	names := append(append([]string{}, parentNames...), this.name)
+	status := this.status()
+	if status == Failed || (status == NotRun && this.children.Len() == 0) {
+		return append(failed, &failedSpec{names, this.path})
+	}
+	for e := this.children.Front(); e != nil; e = e.Next() {
+		failed = e.Value.(*specResult).appendFailedSpecs(failed, names)
+	}
+	return failed
+}
+
+func saveFailures(out io.Writer, failed []*failedSpec) error {
+	return json.NewEncoder(out).Encode(&savedFailures{failed})
+}
+
+func loadFailures(in io.Reader) ([]*failedSpec, error) {
+	saved := &savedFailures{}
+	if err := json.NewDecoder(in).Decode(saved); err != nil {
+		return nil, err
+	}
+	return saved.Failed, nil
+}
+
+// Writes the failed specs to the file, or removes the file if there
+// were no failures, so that the next rerun will execute all specs.
+func saveFailuresFile(path string, results *ResultCollector) error {
+	failed := results.failedSpecs()
+	if len(failed) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	return writeFile(path, func(out io.Writer) error {
+		return saveFailures(out, failed)
+	})
+}
+
+// Returns no failed specs if the file does not exist.
+func loadFailuresFile(path string) ([]*failedSpec, error) {
+	in, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	return loadFailures(in)
+}
@@ -0,0 +1,112 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"bytes"
+	"github.com/orfjackal/nanospec.go/src/nanospec"
+)
+
+func rerunFailedSpecs(failed []*failedSpec, specs ...func(Context)) *Runner {
+	r := NewRunner()
+	for i, spec := range specs {
+		r.AddNamedSpec([]string{"RootSpec", "OtherSpec"}[i], spec)
+	}
+	r.rerunFailed(failed)
+	r.RunSerially()
+	return r
+}
+
+func FailuresFileSpec(c nanospec.Context) {
+
+	c.Specify("The failed specs are identified by their path and names", func() {
+		results := runSpec(func(c Context) {
+			c.Specify("Passing", func() {})
+			c.Specify("Parent", func() {
+				c.Specify("Failing", func() {
+					c.Expect(1, Equals, 2)
+				})
+			})
+		})
+		failed := results.failedSpecs()
+		c.Expect(len(failed)).Equals(1)
+		c.Expect(failed[0].Names).Equals([]string{"RootSpec", "Parent", "Failing"})
+		c.Expect(failed[0].Path).Equals([]int{1, 0})
+	})
+
+	c.Specify("The children of a failed spec are not included, because they are rerun with it", func() {
+		results := runSpec(func(c Context) {
+			c.Specify("Failing", func() {
+				c.Expect(1, Equals, 2)
+				c.Specify("Failing child", func() {
+					c.Expect(1, Equals, 2)
+				})
+			})
+		})
+		failed := results.failedSpecs()
+		c.Expect(len(failed)).Equals(1)
+		c.Expect(failed[0].Names).Equals([]string{"RootSpec", "Failing"})
+	})
+
+	c.Specify("Saved failed specs can be loaded", func() {
+		buf := new(bytes.Buffer)
+		err := saveFailures(buf, []*failedSpec{{[]string{"RootSpec", "a"}, []int{0}}})
+		c.Expect(err).Equals(nil)
+		loaded, err := loadFailures(buf)
+		c.Expect(err).Equals(nil)
+		c.Expect(len(loaded)).Equals(1)
+		c.Expect(loaded[0].Names).Equals([]string{"RootSpec", "a"})
+		c.Expect(loaded[0].Path).Equals([]int{0})
+	})
+
+	c.Specify("When rerunning the failed specs", func() {
+		resetTestSpy()
+		spec := func(c Context) {
+			c.Specify("a", func() {
+				testSpy += "a"
+				c.Specify("aa", func() { testSpy += "aa" })
+				c.Specify("ab", func() { testSpy += "ab" })
+			})
+			c.Specify("b", func() { testSpy += "b" })
+		}
+
+		c.Specify("only the failed specs and their parents are executed", func() {
+			rerunFailedSpecs([]*failedSpec{{[]string{"RootSpec", "a", "ab"}, []int{0, 1}}}, spec)
+			c.Expect(testSpy).Equals("aab")
+		})
+		c.Specify("the children of the failed specs are executed", func() {
+			rerunFailedSpecs([]*failedSpec{{[]string{"RootSpec", "a"}, []int{0}}}, spec)
+			c.Expect(testSpy).Equals("aaaaab")
+		})
+		c.Specify("the root specs without failed specs are not executed", func() {
+			other := func(c Context) { testSpy += "other" }
+			rerunFailedSpecs([]*failedSpec{{[]string{"OtherSpec"}, []int{}}}, spec, other)
+			c.Expect(testSpy).Equals("other")
+		})
+		c.Specify("the failed specs are found by their names, if a spec has been added before them", func() {
+			changed := func(c Context) {
+				c.Specify("new", func() { testSpy += "new" })
+				spec(c)
+			}
+			rerunFailedSpecs([]*failedSpec{{[]string{"RootSpec", "b"}, []int{1}}}, changed)
+			c.Expect(testSpy).Equals("b")
+		})
+		c.Specify("the failed specs are found by their names, if a spec has been removed before them", func() {
+			rerunFailedSpecs([]*failedSpec{{[]string{"RootSpec", "b"}, []int{2}}}, spec)
+			c.Expect(testSpy).Equals("b")
+		})
+		c.Specify("it is an error, if the root specs of the failed specs have been removed", func() {
+			r := NewRunner()
+			r.AddNamedSpec("RootSpec", spec)
+			err := r.rerunFailed([]*failedSpec{{[]string{"RemovedSpec", "a"}, []int{0}}})
+			c.Expect(err != nil).IsTrue()
+			c.Expect(len(r.scheduled)).Equals(1)
+		})
+		c.Specify("nothing is executed, if the failed specs have been removed", func() {
+			rerunFailedSpecs([]*failedSpec{{[]string{"RootSpec", "c", "ca"}, []int{2, 0}}}, spec)
+			c.Expect(testSpy).Equals("")
+		})
+	})
+}
@@ -0,0 +1,175 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Selects specs by their names. The focus and skip patterns are regular
+// expressions, which are matched against the whole nested name of a spec,
+// in which the names of the spec and its parents are separated by slashes,
+// the root spec being the first. Whether a spec has children which match
+// the focus pattern is known only after executing it, so the specs which do
+// not match are executed for finding their children, but they are reported
+// only if some of their children match.
+//
+// The subtest patterns select the specs the same way as gotest's -run and
+// -skip parameters select subtests: the pattern is split by slashes into
+// regular expressions, each of which is matched against the subtest name
+// of the spec on the corresponding nesting level. A spec must be selected
+// by both kinds of patterns.
+type nameFilter struct {
+	focus        *regexp.Regexp
+	skip         *regexp.Regexp
+	subtestFocus []*regexp.Regexp
+	subtestSkip  []*regexp.Regexp
+}
+
+func newNameFilter() *nameFilter {
+	return &nameFilter{}
+}
+
+func (f *nameFilter) setFocus(pattern string) (err error) {
+	f.focus, err = compileNamePattern(pattern)
+	return
+}
+
+func (f *nameFilter) setSkip(pattern string) (err error) {
+	f.skip, err = compileNamePattern(pattern)
+	return
+}
+
+func (f *nameFilter) setSubtestFocus(pattern string) (err error) {
+	f.subtestFocus, err = compileSubtestPattern(pattern)
+	return
+}
+
+func (f *nameFilter) setSubtestSkip(pattern string) (err error) {
+	f.subtestSkip, err = compileSubtestPattern(pattern)
+	return
+}
+
+func compileNamePattern(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	return regexp.Compile(pattern)
+}
+
+func compileSubtestPattern(pattern string) ([]*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	result := []*regexp.Regexp{}
+	for _, part := range splitNamePattern(pattern) {
+		re, err := regexp.Compile(part)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, re)
+	}
+	return result, nil
+}
+
+// Removes the first levels of the pattern, for example the levels which
+// gotest uses for selecting the test function which executes the specs.
+func dropNamePatternLevels(pattern string, levels int) string {
+	parts := splitNamePattern(pattern)
+	if pattern == "" || len(parts) <= levels {
+		return ""
+	}
+	return strings.Join(parts[levels:], "/")
+}
+
+// Splits at the slashes which are not inside brackets or parentheses,
+// because those slashes are part of the regular expression.
+func splitNamePattern(pattern string) []string {
+	parts := []string{}
+	depth := 0
+	start := 0
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '[', '(':
+			depth++
+		case ']', ')':
+			depth--
+		case '\\':
+			i++
+		case '/':
+			if depth == 0 {
+				parts = append(parts, pattern[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, pattern[start:])
+}
+
+// Tells whether the spec or one of its parents matches the focus pattern,
+// and none of them matches the skip pattern. All children of a selected
+// spec are selected.
+func (f *nameFilter) selects(spec *specRun) bool {
+	return f.isFocused(spec) && !f.isSkipped(spec)
+}
+
+func (f *nameFilter) isFocused(spec *specRun) bool {
+	return f.focus == nil || matchesSpecOrParents(f.focus, spec)
+}
+
+// Tells whether the spec must not be executed, because neither it nor its
+// children can be selected.
+func (f *nameFilter) isSkipped(spec *specRun) bool {
+	return (f.skip != nil && matchesSpecOrParents(f.skip, spec)) ||
+		!f.matchesSubtestLevels(spec) || f.isSkippedSubtest(spec)
+}
+
+func matchesSpecOrParents(pattern *regexp.Regexp, spec *specRun) bool {
+	for current := spec; current != nil; current = current.parent {
+		if pattern.MatchString(nestedName(current)) {
+			return true
+		}
+	}
+	return false
+}
+
+func nestedName(spec *specRun) string {
+	if spec.parent == nil {
+		return spec.name
+	}
+	return nestedName(spec.parent) + "/" + spec.name
+}
+
+// Specs nested deeper than the subtest focus pattern match it, if their
+// parents match all levels of the pattern.
+func (f *nameFilter) matchesSubtestLevels(spec *specRun) bool {
+	for current := spec; current != nil; current = current.parent {
+		level := len(current.path)
+		if level < len(f.subtestFocus) && !f.subtestFocus[level].MatchString(subtestNameOf(current)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Specs nested deeper than the subtest skip pattern are skipped, if their
+// parents match all levels of the pattern.
+func (f *nameFilter) isSkippedSubtest(spec *specRun) bool {
+	if len(f.subtestSkip) == 0 || len(spec.path) < len(f.subtestSkip)-1 {
+		return false
+	}
+	for current := spec; current != nil; current = current.parent {
+		level := len(current.path)
+		if level < len(f.subtestSkip) && !f.subtestSkip[level].MatchString(subtestNameOf(current)) {
+			return false
+		}
+	}
+	return true
+}
+
+func subtestNameOf(spec *specRun) string {
+	return subtestName(spec.name, spec.path)
+}
@@ -0,0 +1,138 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"github.com/orfjackal/nanospec.go/src/nanospec"
+)
+
+func FilterSpec(c nanospec.Context) {
+	resetTestSpy()
+	r := NewRunner()
+	r.AddNamedSpec("RootSpec", DummySpecWithMultipleNestedChildren)
+	r.AddNamedSpec("OtherSpec", DummySpecWithNoChildren)
+
+	c.Specify("When no filters are given, all specs are executed", func() {
+		r.RunSerially()
+		c.Expect(testSpy).Equals("rootroot" + "root,a,aaroot,a,abroot,b,baroot,b,bbroot,b,bc")
+	})
+
+	c.Specify("When focusing on some specs", func() {
+
+		c.Specify("the specs which do not match are executed for finding their children", func() {
+			r.Focus("Child AB")
+			r.RunSerially()
+			c.Expect(testSpy).Equals("rootroot" + "root,a,aaroot,a,abroot,b,baroot,b,bbroot,b,bc")
+		})
+		c.Specify("all children of the matching specs are reported", func() {
+			r.Focus("RootSpec/Child B")
+			r.Run()
+			c.Expect(r.Results()).Matches(ReportIs(`
+- RootSpec
+  - Child B
+    - Child BA
+    - Child BB
+    - Child BC
+
+5 specs, 0 failures
+`))
+		})
+		c.Specify("the pattern is matched against the whole nested name", func() {
+			r.Focus("Root.*B[AC]$")
+			r.Run()
+			c.Expect(r.Results()).Matches(ReportIs(`
+- RootSpec
+  - Child B
+    - Child BA
+    - Child BC
+
+4 specs, 0 failures
+`))
+		})
+		c.Specify("only the specs matching the pattern are reported", func() {
+			r.Focus("RootSpec/Child A/Child AB")
+			r.Run()
+			c.Expect(r.Results()).Matches(ReportIs(`
+- RootSpec
+  - Child A
+    - Child AB
+
+3 specs, 0 failures
+`))
+		})
+	})
+
+	c.Specify("When excluding some specs", func() {
+
+		c.Specify("the matching specs and their children are not executed", func() {
+			r.Exclude("RootSpec/Child B")
+			r.RunSerially()
+			c.Expect(testSpy).Equals("rootroot" + "root,a,aaroot,a,ab")
+		})
+		c.Specify("all levels of the pattern must match", func() {
+			r.Exclude("OtherSpec/Child B")
+			r.RunSerially()
+			c.Expect(testSpy).Equals("rootroot" + "root,a,aaroot,a,abroot,b,baroot,b,bbroot,b,bc")
+		})
+		c.Specify("the specs nested deeper than the pattern are not selected", func() {
+			f := newNameFilter()
+			f.setSkip("RootSpec/Child B")
+			root := newSpecRun("RootSpec", nil, nil, nil)
+			childA := newSpecRun("Child A", nil, root, nil)
+			childB := newSpecRun("Child B", nil, root, nil)
+			c.Expect(f.selects(newSpecRun("Child AA", nil, childA, nil))).IsTrue()
+			c.Expect(f.selects(newSpecRun("Child BA", nil, childB, nil))).IsFalse()
+		})
+	})
+
+	c.Specify("When a spec which does not match the focus pattern fails", func() {
+		r := NewRunner()
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			c.Specify("Failing", func() {
+				c.Expect(1, Equals, 2)
+			})
+			c.Specify("Matching", func() {})
+		})
+		r.Focus("Matching")
+		r.Run()
+
+		c.Specify("it is not reported", func() {
+			c.Expect(r.Results()).Matches(ReportIs(`
+- RootSpec
+  - Matching
+
+2 specs, 0 failures
+`))
+		})
+	})
+
+	c.Specify("Specs whose names contain slashes can be selected", func() {
+		r := NewRunner()
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			c.Specify("stack/queue", func() {})
+			c.Specify("stack", func() {})
+		})
+		r.Focus("RootSpec/stack/queue")
+		r.Run()
+		c.Expect(r.Results()).Matches(ReportIs(`
+- RootSpec
+  - stack/queue
+
+2 specs, 0 failures
+`))
+	})
+
+	c.Specify("Invalid patterns are reported as errors", func() {
+		err := r.Focus("Root/(")
+		c.Expect(err != nil).IsTrue()
+		err = r.Exclude("[")
+		c.Expect(err != nil).IsTrue()
+	})
+
+	c.Specify("Slashes inside brackets and parentheses do not split the pattern", func() {
+		c.Expect(splitNamePattern("a/b[/]c/(d/e)")).Equals([]string{"a", "b[/]c", "(d/e)"})
+		c.Expect(splitNamePattern(`a\/b/c`)).Equals([]string{`a\/b`, "c"})
+	})
+}
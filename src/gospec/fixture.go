@@ -0,0 +1,80 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// A fixture which is set up only once and then shared by all executions
+// of a root spec. The root spec is executed once for every leaf spec, so
+// the fixture avoids repeating expensive setup, but it also means that
+// the specs must not modify the fixture.
+type sharedFixture struct {
+	rootName string
+	setup    func() (interface{}, error)
+	once     sync.Once
+	value    interface{}
+	err      error
+}
+
+func newSharedFixture(rootName string, setup func() (interface{}, error)) *sharedFixture {
+	return &sharedFixture{rootName: rootName, setup: setup}
+}
+
+// The fixture is set up lazily, so that the fixtures of root specs which
+// are not selected for execution are never set up.
+func (f *sharedFixture) get() (interface{}, error) {
+	f.once.Do(func() {
+		// If the setup panics, the panic is reported by the first execution
+		// and the later executions will see this error.
+		f.err = errors.New("the setup panicked")
+		f.value, f.err = f.setup()
+	})
+	return f.value, f.err
+}
+
+func (f *sharedFixture) wrap(closure func(Context, interface{})) func(Context) {
+	return func(c Context) {
+		value, err := f.get()
+		if err != nil {
+			c.(*taskContext).failCurrentSpec(fmt.Sprintf("Fixture setup failed: %v", err))
+			return
+		}
+		closure(c, value)
+	}
+}
+
+// Closes the fixture if it was set up and it implements io.Closer.
+func (f *sharedFixture) close() error {
+	var closer io.Closer
+	f.once.Do(func() {
+		f.err = errors.New("the fixture was not used")
+	})
+	if f.err == nil {
+		closer, _ = f.value.(io.Closer)
+	}
+	if closer == nil {
+		return nil
+	}
+	return closer.Close()
+}
+
+// Calls a function which is executed once for the whole suite, such as
+// BeforeAll. Returns the error describing its failure, or nil.
+func callSuiteFunction(description string, f func() error) *Error {
+	var err error
+	exception := recoverOnPanic(func() { err = f() })
+	if exception != nil {
+		return newError(OtherError, fmt.Sprintf("%v failed: %v", description, exception), "", exception.StackTrace)
+	}
+	if err != nil {
+		return newError(OtherError, fmt.Sprintf("%v failed: %v", description, err), "", []*Location{})
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"errors"
+	"github.com/orfjackal/nanospec.go/src/nanospec"
+)
+
+func FixtureSpec(c nanospec.Context) {
+	resetTestSpy()
+	r := NewRunner()
+
+	c.Specify("BeforeAll and AfterAll are called once around all the specs", func() {
+		r.BeforeAll(func() error {
+			testSpy += "before,"
+			return nil
+		})
+		r.AfterAll(func() error {
+			testSpy += "after"
+			return nil
+		})
+		r.AddNamedSpec("RootSpec1", func(c Context) {
+			testSpy += "root1,"
+		})
+		r.AddNamedSpec("RootSpec2", func(c Context) {
+			testSpy += "root2,"
+		})
+		r.RunSerially()
+		c.Expect(testSpy).Equals("before,root1,root2,after")
+	})
+
+	c.Specify("When BeforeAll fails", func() {
+		r.BeforeAll(func() error {
+			return errors.New("no database")
+		})
+		r.AfterAll(func() error {
+			testSpy += "after"
+			return nil
+		})
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			testSpy += "root,"
+		})
+		r.Run()
+		results := r.Results()
+
+		c.Specify("then the specs are not executed, but AfterAll is", func() {
+			c.Expect(testSpy).Equals("after")
+		})
+		c.Specify("then the specs fail with the error as the cause", func() {
+			c.Expect(results).Matches(ReportIs(`
+- RootSpec [FAIL]
+*** BeforeAll failed: no database
+
+1 specs, 1 failures
+`))
+		})
+	})
+
+	c.Specify("When AfterAll fails, then the specs fail with the error as the cause", func() {
+		r.AfterAll(func() error {
+			return errors.New("cleanup failed")
+		})
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			c.Specify("Child", func() {})
+		})
+		r.Run()
+		c.Expect(r.Results()).Matches(ReportIs(`
+- RootSpec [FAIL]
+*** AfterAll failed: cleanup failed
+  - Child
+
+2 specs, 1 failures
+`))
+	})
+
+	c.Specify("When a spec has a shared fixture", func() {
+		fixture := &DummyFixture{}
+		setupCount := 0
+		r.AddNamedSpecWithFixture("RootSpec", func() (interface{}, error) {
+			setupCount++
+			return fixture, nil
+		}, func(c Context, value interface{}) {
+			testSpy += "root,"
+			c.Expect(value, Equals, fixture)
+			c.Specify("Child A", func() {})
+			c.Specify("Child B", func() {})
+		})
+		r.Run()
+		results := r.Results()
+
+		c.Specify("then the fixture is set up only once", func() {
+			c.Expect(testSpy).Equals("root,root,root,")
+			c.Expect(setupCount).Equals(1)
+		})
+		c.Specify("then the same fixture is given to every execution of the spec", func() {
+			c.Expect(results.FailCount()).Equals(0)
+		})
+		c.Specify("then the fixture is closed after all the specs", func() {
+			c.Expect(fixture.closed).IsTrue()
+		})
+	})
+
+	c.Specify("When setting up a shared fixture fails", func() {
+		r.AddNamedSpecWithFixture("RootSpec", func() (interface{}, error) {
+			return nil, errors.New("no server")
+		}, func(c Context, value interface{}) {
+			testSpy += "root,"
+		})
+		r.Run()
+		results := r.Results()
+
+		c.Specify("then the spec's closure is not executed", func() {
+			c.Expect(testSpy).Equals("")
+		})
+		c.Specify("then the spec fails with the error as the cause", func() {
+			c.Expect(results).Matches(ReportIs(`
+- RootSpec [FAIL]
+*** Fixture setup failed: no server
+
+1 specs, 1 failures
+`))
+		})
+	})
+}
+
+type DummyFixture struct {
+	closed bool
+}
+
+func (f *DummyFixture) Close() error {
+	f.closed = true
+	return nil
+}
@@ -0,0 +1,49 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"sync"
+)
+
+// Keeps track of the specs which have been focused with FSpecify, so that
+// the specs which are not related to them are not executed. A spec is
+// known to be focused only after its parent has been executed. The Runner
+// executes every root spec first without executing its children, so the
+// focused children of the root specs are known before executing any other
+// specs, but the specs which are executed before finding a more deeply
+// nested focused spec, for example its earlier siblings, are executed and
+// reported normally. The tasks of all root specs share the same instance.
+type focusedSpecs struct {
+	mutex       sync.Mutex
+	pathsByRoot map[string][]path
+}
+
+func newFocusedSpecs() *focusedSpecs {
+	return &focusedSpecs{pathsByRoot: make(map[string][]path)}
+}
+
+func (f *focusedSpecs) add(spec *specRun) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	root := spec.rootParent().name
+	f.pathsByRoot[root] = append(f.pathsByRoot[root], spec.path)
+}
+
+// The parents and children of the focused specs are related to them.
+// When no specs are focused, all specs are related.
+func (f *focusedSpecs) isUnrelated(spec *specRun) bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if len(f.pathsByRoot) == 0 {
+		return false
+	}
+	for _, focused := range f.pathsByRoot[spec.rootParent().name] {
+		if spec.path.isOn(focused) || focused.isOn(spec.path) {
+			return false
+		}
+	}
+	return true
+}
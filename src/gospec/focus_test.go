@@ -0,0 +1,228 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"github.com/orfjackal/nanospec.go/src/nanospec"
+)
+
+func FocusSpec(c nanospec.Context) {
+
+	c.Specify("When a spec is disabled with XSpecify", func() {
+		resetTestSpy()
+		results := runSpec(func(c Context) {
+			testSpy += "root"
+			c.XSpecify("Disabled", func() {
+				testSpy += ",disabled"
+			})
+			c.Specify("Enabled", func() {
+				testSpy += ",enabled"
+			})
+		})
+
+		c.Specify("its closure is not executed", func() {
+			c.Expect(testSpy).Equals("root" + "root,enabled")
+		})
+		c.Specify("it is reported as skipped", func() {
+			c.Expect(results.SkipCount()).Equals(1)
+			c.Expect(results).Matches(ReportIs(`
+- RootSpec
+  - Disabled [SKIP]
+  - Enabled
+
+3 specs, 0 failures, 1 skipped
+`))
+		})
+	})
+
+	c.Specify("When a spec is pending", func() {
+		results := runSpec(func(c Context) {
+			c.Pending("Not yet specified")
+			c.Specify("Specified", func() {
+			})
+		})
+
+		c.Specify("it is reported as pending", func() {
+			c.Expect(results.PendingCount()).Equals(1)
+			c.Expect(results).Matches(ReportIs(`
+- RootSpec
+  - Not yet specified [PENDING]
+  - Specified
+
+3 specs, 0 failures, 1 pending
+`))
+		})
+	})
+
+	c.Specify("When a spec is skipped with Skip", func() {
+		resetTestSpy()
+		results := runSpec(func(c Context) {
+			testSpy += "root"
+			c.Specify("Skipped", func() {
+				testSpy += ",skipped"
+				c.Skipf("requires %v", "docker")
+				testSpy += ",after skip"
+				c.Specify("Child of skipped", func() {
+				})
+			})
+			c.Specify("Not skipped", func() {
+				testSpy += ",not skipped"
+			})
+		})
+
+		c.Specify("the rest of its closure is not executed", func() {
+			c.Expect(testSpy).Equals("root" + "root,skippedroot,not skipped")
+		})
+		c.Specify("it is reported as skipped with the reason", func() {
+			c.Expect(results.SkipCount()).Equals(1)
+			c.Expect(results.FailCount()).Equals(0)
+			c.Expect(results).Matches(ReportIs(`
+- RootSpec
+  - Skipped [SKIP: requires docker]
+  - Not skipped
+
+3 specs, 0 failures, 1 skipped
+`))
+		})
+	})
+
+	c.Specify("When some specs are focused with FSpecify", func() {
+		resetTestSpy()
+		results := runSpec(func(c Context) {
+			c.Specify("Parent of focused", func() {
+				c.FSpecify("Focused", func() {
+					testSpy += "focused,"
+					c.Specify("Child of focused", func() {
+						testSpy += "child,"
+					})
+				})
+				c.Specify("Sibling of focused", func() {
+					testSpy += "sibling,"
+					c.Expect(1, Equals, 2)
+				})
+			})
+			c.Specify("Unrelated", func() {
+				testSpy += "unrelated,"
+			})
+		})
+
+		c.Specify("only the focused specs, their parents and children are executed", func() {
+			c.Expect(testSpy).Equals("focused,child,")
+		})
+		c.Specify("the other specs are reported as skipped", func() {
+			c.Expect(results.HasFocusedSpecs()).IsTrue()
+			c.Expect(results).Matches(ReportIs(`
+- RootSpec
+  - Parent of focused
+    - Focused
+      - Child of focused
+    - Sibling of focused [SKIP]
+  - Unrelated [SKIP]
+
+6 specs, 0 failures, 2 skipped
+`))
+		})
+	})
+
+	c.Specify("When a spec is focused in one root spec", func() {
+		resetTestSpy()
+		r := NewRunner()
+		r.AddNamedSpec("FocusedSpec", func(c Context) {
+			c.FSpecify("Focused", func() {})
+		})
+		r.AddNamedSpec("OtherSpec", func(c Context) {
+			testSpy += "other root,"
+			c.Specify("Child", func() {
+				testSpy += "other child,"
+			})
+		})
+		r.RunSerially()
+		results := r.Results()
+
+		c.Specify("the other root specs are not executed", func() {
+			c.Expect(testSpy).Equals("")
+			c.Expect(results).Matches(ReportIs(`
+- FocusedSpec
+  - Focused
+- OtherSpec [SKIP]
+
+3 specs, 0 failures, 1 skipped
+`))
+		})
+	})
+
+	c.Specify("When a spec is focused in a later root spec and the specs are executed in parallel", func() {
+		resetTestSpy()
+		r := NewRunner()
+		r.SetParallelism(4)
+		for _, name := range []string{"FirstSpec", "SecondSpec", "ThirdSpec"} {
+			r.AddNamedSpec(name, func(c Context) {
+				c.Specify("Child A", func() {
+					testSpy += "child,"
+					c.Expect(1, Equals, 2)
+				})
+				c.Specify("Child B", func() {
+					testSpy += "child,"
+					c.Expect(1, Equals, 2)
+				})
+			})
+		}
+		r.AddNamedSpec("FocusedSpec", func(c Context) {
+			c.Specify("Unrelated", func() {
+				testSpy += "unrelated,"
+				c.Expect(1, Equals, 2)
+			})
+			c.FSpecify("Focused", func() {
+				testSpy += "focused,"
+			})
+		})
+		r.Run()
+		results := r.Results()
+
+		c.Specify("only the focused specs are executed", func() {
+			c.Expect(testSpy).Equals("focused,")
+			c.Expect(results.FailCount()).Equals(0)
+		})
+	})
+
+	c.Specify("When a spec is declared before a focused spec which is nested deeper", func() {
+		results := runSpec(func(c Context) {
+			c.Specify("Parent", func() {
+				c.Specify("Executed before focus was found", func() {
+					c.Expect(1, Equals, 2)
+				})
+				c.FSpecify("Focused", func() {})
+			})
+		})
+
+		c.Specify("it is reported normally, because it was already executed", func() {
+			c.Expect(results.FailCount()).Equals(1)
+			c.Expect(results.SkipCount()).Equals(0)
+		})
+	})
+
+	c.Specify("When a spec is declared before a focused spec in a root spec", func() {
+		results := runSpec(func(c Context) {
+			c.Specify("Declared before focus", func() {
+				c.Expect(1, Equals, 2)
+			})
+			c.FSpecify("Focused", func() {})
+		})
+
+		c.Specify("it is skipped, because the children of the root specs are declared before executing them", func() {
+			c.Expect(results.FailCount()).Equals(0)
+			c.Expect(results.SkipCount()).Equals(1)
+		})
+	})
+
+	c.Specify("When no specs are focused", func() {
+		results := runSpec(DummySpecWithTwoChildren)
+
+		c.Specify("all specs are reported", func() {
+			c.Expect(results.HasFocusedSpecs()).Equals(false)
+			c.Expect(results.SkipCount()).Equals(0)
+		})
+	})
+}
@@ -0,0 +1,130 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+)
+
+// The JUnit XML format, as understood by most CI servers. Every root spec
+// is a test suite, and every spec without children is a test case. Failed
+// expectations and assumptions are failures, and other errors are errors.
+type junitTestSuites struct {
+	XMLName xml.Name          `xml:"testsuites"`
+	Suites  []*junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Cases    []*junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string          `xml:"name,attr"`
+	ClassName string          `xml:"classname,attr"`
+	Time      string          `xml:"time,attr"`
+	Failures  []*junitProblem `xml:"failure"`
+	Errors    []*junitProblem `xml:"error"`
+	Skipped   *junitSkipped   `xml:"skipped"`
+	SystemOut string          `xml:"system-out,omitempty"`
+}
+
+type junitProblem struct {
+	Type    string `xml:"type,attr"`
+	Message string `xml:"message,attr"`
+	Body    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+// Writes the results as JUnit XML.
+func (r *ResultCollector) WriteJUnit(out io.Writer) error {
+	suites := &junitTestSuites{}
+	for root := range r.sortedRoots() {
+		suite := &junitTestSuite{Name: root.name, Time: junitTime(root.duration)}
+		root.appendTestCases(suite, root.name)
+		suites.Suites = append(suites.Suites, suite)
+	}
+	if _, err := io.WriteString(out, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(out)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(out, "\n")
+	return err
+}
+
+// The test cases are named by the names of the specs below the root spec.
+// Also the parent specs which have errors are test cases, so that their
+// errors are not lost.
+func (this *specResult) appendTestCases(suite *junitTestSuite, name string) {
+	if this.children.Len() == 0 || this.hasFailures() {
+		suite.addTestCase(this.newTestCase(name, suite.Name))
+	}
+	for e := this.children.Front(); e != nil; e = e.Next() {
+		child := e.Value.(*specResult)
+		childName := child.name
+		if !this.path.isRoot() {
+			childName = name + " / " + child.name
+		}
+		child.appendTestCases(suite, childName)
+	}
+}
+
+func (this *specResult) newTestCase(name string, className string) *junitTestCase {
+	testCase := &junitTestCase{Name: name, ClassName: className, Time: junitTime(this.duration)}
+	errors := listToErrorArray(this.errors)
+	switch status := this.status(); status {
+	case Failed:
+		for _, error := range errors {
+			problem := &junitProblem{error.Type.String(), error.Message, formatErrorWithStackTrace(error)}
+			switch error.Type {
+			case ExpectFailed, AssumeFailed:
+				testCase.Failures = append(testCase.Failures, problem)
+			case OtherError, TimedOut:
+				testCase.Errors = append(testCase.Errors, problem)
+			}
+		}
+	case Skipped, Pending, Aborted, NotRun:
+		reason := skipReason(errors)
+		if reason == "" {
+			reason = status.String()
+		}
+		testCase.Skipped = &junitSkipped{reason}
+	case Flaky:
+		testCase.SystemOut = statusLabel(status, "")
+	}
+	return testCase
+}
+
+func (suite *junitTestSuite) addTestCase(testCase *junitTestCase) {
+	suite.Cases = append(suite.Cases, testCase)
+	suite.Tests++
+	switch {
+	case len(testCase.Errors) > 0:
+		suite.Errors++
+	case len(testCase.Failures) > 0:
+		suite.Failures++
+	case testCase.Skipped != nil:
+		suite.Skipped++
+	}
+}
+
+func junitTime(duration time.Duration) string {
+	return fmt.Sprintf("%.3f", duration.Seconds())
+}
@@ -0,0 +1,139 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"bytes"
+	"encoding/xml"
+	"github.com/orfjackal/nanospec.go/src/nanospec"
+	"strings"
+)
+
+func writeAndParseJUnit(results *ResultCollector) (string, *junitTestSuites) {
+	buf := new(bytes.Buffer)
+	if err := results.WriteJUnit(buf); err != nil {
+		panic(err)
+	}
+	suites := &junitTestSuites{}
+	if err := xml.Unmarshal(buf.Bytes(), suites); err != nil {
+		panic(err)
+	}
+	return buf.String(), suites
+}
+
+func JUnitSpec(c nanospec.Context) {
+
+	c.Specify("The report is an XML document", func() {
+		report, _ := writeAndParseJUnit(runSpec(func(c Context) {}))
+		c.Expect(strings.HasPrefix(report, `<?xml version="1.0" encoding="UTF-8"?>`)).IsTrue()
+	})
+
+	c.Specify("Every root spec is a test suite", func() {
+		r := NewRunner()
+		r.AddNamedSpec("RootSpec1", DummySpecWithOneChild)
+		r.AddNamedSpec("RootSpec2", DummySpecWithTwoChildren)
+		r.Run()
+		_, suites := writeAndParseJUnit(r.Results())
+		c.Expect(len(suites.Suites)).Equals(2)
+		c.Expect(suites.Suites[0].Name).Equals("RootSpec1")
+		c.Expect(suites.Suites[0].Tests).Equals(1)
+		c.Expect(suites.Suites[1].Name).Equals("RootSpec2")
+		c.Expect(suites.Suites[1].Tests).Equals(2)
+	})
+
+	c.Specify("The leaf specs are test cases named by the specs below the root spec", func() {
+		_, suites := writeAndParseJUnit(runSpec(func(c Context) {
+			c.Specify("Parent", func() {
+				c.Specify("Child", func() {})
+			})
+			c.Specify("Leaf", func() {})
+		}))
+		cases := suites.Suites[0].Cases
+		c.Expect(len(cases)).Equals(2)
+		c.Expect(cases[0].Name).Equals("Parent / Child")
+		c.Expect(cases[0].ClassName).Equals("RootSpec")
+		c.Expect(cases[1].Name).Equals("Leaf")
+	})
+
+	c.Specify("Failed expectations and assumptions are failures", func() {
+		_, suites := writeAndParseJUnit(runSpec(func(c Context) {
+			c.Specify("Expect", func() {
+				c.Expect(1, Equals, 2)
+			})
+			c.Specify("Assume", func() {
+				c.Assume(1, Equals, 2)
+			})
+		}))
+		suite := suites.Suites[0]
+		c.Expect(suite.Failures).Equals(2)
+		c.Expect(suite.Errors).Equals(0)
+		c.Expect(len(suite.Cases[0].Failures)).Equals(1)
+		c.Expect(suite.Cases[0].Failures[0].Type).Equals("ExpectFailed")
+		c.Expect(suite.Cases[0].Failures[0].Message).Equals("equals “2”")
+		c.Expect(suite.Cases[1].Failures[0].Type).Equals("AssumeFailed")
+	})
+
+	c.Specify("Other errors are errors", func() {
+		_, suites := writeAndParseJUnit(runSpec(func(c Context) {
+			c.Specify("Panic", func() {
+				panic("boom")
+			})
+		}))
+		suite := suites.Suites[0]
+		c.Expect(suite.Failures).Equals(0)
+		c.Expect(suite.Errors).Equals(1)
+		c.Expect(suite.Cases[0].Errors[0].Type).Equals("OtherError")
+		c.Expect(suite.Cases[0].Errors[0].Message).Equals("panic: boom")
+	})
+
+	c.Specify("The body of a failure has the error message and the stack trace", func() {
+		_, suites := writeAndParseJUnit(runSpec(func(c Context) {
+			c.Specify("Expect", func() {
+				c.Expect(1, Equals, 2)
+			})
+		}))
+		body := suites.Suites[0].Cases[0].Failures[0].Body
+		c.Expect(strings.HasPrefix(body, "*** Expected: equals “2”\n         got: “1”\n    at ")).IsTrue()
+		c.Expect(strings.Contains(body, "junit_test.go:")).IsTrue()
+	})
+
+	c.Specify("The errors of parent specs are not lost", func() {
+		_, suites := writeAndParseJUnit(runSpec(func(c Context) {
+			c.Specify("Failing parent", func() {
+				c.Expect(1, Equals, 2)
+				c.Specify("Child", func() {})
+			})
+		}))
+		cases := suites.Suites[0].Cases
+		c.Expect(len(cases)).Equals(2)
+		c.Expect(cases[0].Name).Equals("Failing parent")
+		c.Expect(len(cases[0].Failures)).Equals(1)
+		c.Expect(cases[1].Name).Equals("Failing parent / Child")
+	})
+
+	c.Specify("Skipped and pending specs are skipped, with their reason", func() {
+		_, suites := writeAndParseJUnit(runSpec(func(c Context) {
+			c.XSpecify("Skipped", func() {})
+			c.Specify("Skipped with a reason", func() {
+				c.Skip("some reason")
+			})
+			c.Pending("Pending")
+		}))
+		suite := suites.Suites[0]
+		c.Expect(suite.Skipped).Equals(3)
+		c.Expect(suite.Failures).Equals(0)
+		c.Expect(suite.Cases[0].Skipped.Message).Equals("skipped")
+		c.Expect(suite.Cases[1].Skipped.Message).Equals("some reason")
+		c.Expect(suite.Cases[2].Skipped.Message).Equals("pending")
+	})
+
+	c.Specify("Passing specs have no failures, errors nor skipped elements", func() {
+		report, _ := writeAndParseJUnit(runSpec(func(c Context) {
+			c.Specify("Passing", func() {})
+		}))
+		c.Expect(strings.Contains(report, `<testcase name="Passing" classname="RootSpec" time="`)).IsTrue()
+		c.Expect(strings.Contains(report, `"></testcase>`)).IsTrue()
+	})
+}
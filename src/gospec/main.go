@@ -6,12 +6,36 @@ package gospec
 
 import (
 	"flag"
+	"fmt"
+	"io"
 	"os"
 	"testing"
+	"time"
 )
 
 var (
 	printAll = flag.Bool("print-all", false, "print also passing specs and not only failing (GoSpec)")
+	serial   = flag.Bool("gospec.serial", false, "execute the specs one at a time in declaration order (GoSpec)")
+	shuffle  = flag.Bool("gospec.shuffle", false, "execute the specs in random order (GoSpec)")
+	seed     = flag.Int64("gospec.seed", 0, "seed for the random order, implies -gospec.shuffle (GoSpec)")
+	timeout  = flag.Duration("gospec.timeout", 0, "fail specs which run for longer than this, 0 means no timeout (GoSpec)")
+	focus    = flag.String("gospec.focus", "", "execute only the specs whose slash-separated nested names match `regexp` (GoSpec)")
+	skip     = flag.String("gospec.skip", "", "do not execute the specs whose slash-separated nested names match `regexp` (GoSpec)")
+	failFast = flag.Bool("gospec.failfast", false, "do not start new specs after the first failure (GoSpec)")
+	slowest  = flag.Int("gospec.slowest", 0, "list the `n` slowest specs and root specs after the summary (GoSpec)")
+	retries  = flag.Int("gospec.retries", 0, "retry failed specs up to `n` times, reporting them as flaky if they then pass (GoSpec)")
+
+	failOnFocus = flag.Bool("gospec.fail-on-focus", false, "fail if some specs are focused with FSpecify, for use in CI (GoSpec)")
+	resultsFile = flag.String("gospec.results", "", "write the results as JSON to `file`, for the gospec command (GoSpec)")
+	events      = flag.String("gospec.events", "", "stream the results as newline-delimited JSON events to `file` while the specs are executed (GoSpec)")
+	junitFile   = flag.String("gospec.junit", "", "write the results as JUnit XML to `file`, for CI servers (GoSpec)")
+	testEvents  = flag.String("gospec.test2json", "", "write the results as go test -json events to `file`, for Go's test tools (GoSpec)")
+	failures    = flag.String("gospec.failures", "", "write the failed specs to `file`, for -gospec.rerun-failed (GoSpec)")
+	colorMode   = flag.String("gospec.color", "auto", "color the output: `mode` is always, never or auto, which colors it on a terminal unless NO_COLOR is set (GoSpec)")
+	rerunFailed = flag.Bool("gospec.rerun-failed", false, "execute only the specs in the -gospec.failures file, or all if none failed (GoSpec)")
+
+	shardIndex   = flag.String("gospec.shard", "", "execute only the root specs of shard `i/n`, for splitting the specs between n processes (GoSpec)")
+	shardTimings = flag.String("gospec.shard-timings", "", "balance the shards by the durations in a -gospec.results `file` of a previous run (GoSpec)")
 )
 
 // Executes the specs which have been added to the Runner
@@ -20,8 +44,16 @@ var (
 // depending on whether any specs failed.
 func Main(runner *Runner) {
 	flag.Parse()
+	if err := configure(runner); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
 	results := runAndPrint(runner)
-	if results.FailCount() > 0 {
+	if err := saveResults(results, DefaultTestName); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if hasFailed(results) {
 		os.Exit(1)
 	} else {
 		os.Exit(0)
@@ -36,14 +68,28 @@ func MainGoTest(runner *Runner, t *testing.T) {
 	// flag.Parse() has already been called in testing.Main() so
 	// we don't need to call it here.
 
+	if err := configure(runner); err != nil {
+		t.Fatal(err)
+	}
 	results := runAndPrint(runner)
-	if results.FailCount() > 0 {
+	if err := saveResults(results, t.Name()); err != nil {
+		t.Fatal(err)
+	}
+	if hasFailed(results) {
 		t.Fail()
 	}
 }
 
+func hasFailed(results *ResultCollector) bool {
+	if *failOnFocus && results.HasFocusedSpecs() {
+		fmt.Println("FAIL: Some specs are focused with FSpecify")
+		return true
+	}
+	return results.FailCount() > 0
+}
+
 func runAndPrint(runner *Runner) *ResultCollector {
-	printer := NewPrinter(DefaultPrintFormat(os.Stdout))
+	printer := NewPrinter(newPrintFormat(os.Stdout))
 	if *printAll {
 		printer.ShowAll()
 	} else {
@@ -51,8 +97,150 @@ func runAndPrint(runner *Runner) *ResultCollector {
 	}
 	printer.ShowSummary()
 
-	runner.Run()
-	results := runner.Results()
+	results := run(runner)
+	results.ReportSlowest(*slowest)
 	results.Visit(printer)
 	return results
 }
+
+// The color mode has been validated by configure.
+func newPrintFormat(out io.Writer) PrintFormat {
+	if colors, _ := UseColors(*colorMode, out); colors {
+		return ColorPrintFormat(out)
+	}
+	return DefaultPrintFormat(out)
+}
+
+func run(runner *Runner) *ResultCollector {
+	if *serial {
+		runner.RunSerially()
+	} else {
+		runner.Run()
+	}
+	return runner.Results()
+}
+
+func saveResults(results *ResultCollector, testName string) error {
+	if *failures != "" {
+		if err := saveFailuresFile(*failures, results); err != nil {
+			return err
+		}
+	}
+	if *resultsFile != "" {
+		if err := writeFile(*resultsFile, results.Save); err != nil {
+			return err
+		}
+	}
+	if *junitFile != "" {
+		if err := writeFile(*junitFile, results.WriteJUnit); err != nil {
+			return err
+		}
+	}
+	if *testEvents != "" {
+		err := writeFile(*testEvents, func(out io.Writer) error {
+			return results.WriteTestEvents(out, testName)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Writes the results to the files which were requested with GoSpec's
+// parameters, such as -gospec.results and -gospec.junit. Meant for tools
+// which combine the results of many test processes, such as the gospec
+// command, and write the files once from the combined results.
+func SaveResultFiles(results *ResultCollector, testName string) error {
+	return saveResults(results, testName)
+}
+
+func writeFile(path string, write func(io.Writer) error) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = write(out)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func configure(runner *Runner) error {
+	if _, err := UseColors(*colorMode, os.Stdout); err != nil {
+		return fmt.Errorf("invalid -gospec.color: %v", err)
+	}
+	runner.SetTimeout(*timeout)
+	runner.SetRetries(*retries)
+	if *shuffle || *seed != 0 {
+		runner.Shuffle(randomSeed())
+	}
+	if *failFast {
+		runner.FailFast()
+	}
+	if err := runner.Focus(*focus); err != nil {
+		return fmt.Errorf("invalid -gospec.focus: %v", err)
+	}
+	if err := runner.Exclude(*skip); err != nil {
+		return fmt.Errorf("invalid -gospec.skip: %v", err)
+	}
+	if *events != "" {
+		// The file is closed when the process exits.
+		out, err := os.Create(*events)
+		if err != nil {
+			return fmt.Errorf("invalid -gospec.events: %v", err)
+		}
+		runner.StreamEvents(out)
+	}
+	if *shardIndex != "" {
+		if err := configureShard(runner); err != nil {
+			return fmt.Errorf("invalid -gospec.shard: %v", err)
+		}
+	}
+	if *rerunFailed {
+		if *failures == "" {
+			return fmt.Errorf("-gospec.rerun-failed requires -gospec.failures")
+		}
+		failed, err := loadFailuresFile(*failures)
+		if err != nil {
+			return fmt.Errorf("invalid -gospec.failures: %v", err)
+		}
+		if len(failed) > 0 {
+			if err := runner.rerunFailed(failed); err != nil {
+				return fmt.Errorf("invalid -gospec.failures: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+func configureShard(runner *Runner) error {
+	index, total, err := parseShard(*shardIndex)
+	if err != nil {
+		return err
+	}
+	if err := runner.Shard(index, total); err != nil {
+		return err
+	}
+	if *shardTimings != "" {
+		in, err := os.Open(*shardTimings)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		results, err := LoadResults(in)
+		if err != nil {
+			return err
+		}
+		runner.BalanceShards(results.rootDurations())
+	}
+	return nil
+}
+
+func randomSeed() int64 {
+	if *seed != 0 {
+		return *seed
+	}
+	return time.Now().UnixNano()
+}
@@ -7,21 +7,31 @@ package gospec
 import (
 	"fmt"
 	"io"
+	"strings"
 )
 
 type PrintFormat interface {
 	PrintPassing(nestingLevel int, name string)
 	PrintFailing(nestingLevel int, name string, errors []*Error)
-	PrintSummary(passCount int, failCount int)
+	PrintSkipped(nestingLevel int, name string, status Status, reason string)
+	PrintSummary(summary Summary)
 }
 
 // PrintFormat for production use.
 func DefaultPrintFormat(out io.Writer) PrintFormat {
-	return &defaultPrintFormat{out}
+	return &defaultPrintFormat{out, false}
+}
+
+// PrintFormat for production use on a terminal. Same as DefaultPrintFormat,
+// but the failures are highlighted in red, and the summary is red if some
+// specs failed, else green.
+func ColorPrintFormat(out io.Writer) PrintFormat {
+	return &defaultPrintFormat{out, true}
 }
 
 type defaultPrintFormat struct {
-	out io.Writer
+	out    io.Writer
+	colors bool
 }
 
 func (this *defaultPrintFormat) PrintPassing(nestingLevel int, name string) {
@@ -33,19 +43,22 @@ func (this *defaultPrintFormat) PrintPassing(nestingLevel int, name string) {
 }
 
 func (this *defaultPrintFormat) PrintFailing(nestingLevel int, name string, errors []*Error) {
-	// TODO: use colors (red)
-	fmt.Fprintf(this.out, "%v- %v [FAIL]\n\n", indent(nestingLevel), name)
+	fmt.Fprintf(this.out, "%v- %v %v\n\n", indent(nestingLevel), name, this.color(red, "[FAIL]"))
 	for _, error := range errors {
 		this.printError(error)
 	}
 	fmt.Fprint(this.out, "\n")
 }
 
+func (this *defaultPrintFormat) PrintSkipped(nestingLevel int, name string, status Status, reason string) {
+	fmt.Fprintf(this.out, "%v- %v %v\n", indent(nestingLevel), name, statusLabel(status, reason))
+}
+
 func (this *defaultPrintFormat) printError(error *Error) {
 	// Go's stack trace format can be seen in
 	// traceback() at src/pkg/runtime/amd64/traceback.c
 	// but we don't have to use exactly the same format.
-	fmt.Fprint(this.out, formatErrorMessage(error))
+	fmt.Fprint(this.out, this.color(red, formatErrorMessage(error)))
 	for _, loc := range error.StackTrace {
 		// Keep the function name on a different line than the file path,
 		// because gedit 2.28.0 has a bug which causes the path to be
@@ -56,6 +69,24 @@ func (this *defaultPrintFormat) printError(error *Error) {
 	fmt.Fprintf(this.out, "\n")
 }
 
+func statusLabel(status Status, reason string) string {
+	switch {
+	case status == Skipped && reason != "":
+		return fmt.Sprintf("[SKIP: %v]", reason)
+	case status == Skipped:
+		return "[SKIP]"
+	case status == Pending:
+		return "[PENDING]"
+	case status == Aborted:
+		return "[ABORTED]"
+	case status == NotRun:
+		return "[NOT RUN]"
+	case status == Flaky:
+		return "[FLAKY]"
+	}
+	return ""
+}
+
 func formatErrorMessage(e *Error) string {
 	s := ""
 	switch e.Type {
@@ -65,16 +96,80 @@ func formatErrorMessage(e *Error) string {
 	case AssumeFailed:
 		s += fmt.Sprintf("*** Assumed: %v\n", e.Message)
 		s += fmt.Sprintf("        got: “%v”\n", e.Actual)
-	case OtherError:
+	case OtherError, TimedOut, SkipRequested:
 		s += fmt.Sprintf("*** %v\n", e.Message)
 	}
 	return s
 }
 
-func (this *defaultPrintFormat) PrintSummary(passCount int, failCount int) {
-	totalCount := passCount + failCount
-	// TODO: use colors (red if failures, else green)
-	fmt.Fprintf(this.out, "\n%v specs, %v failures\n", totalCount, failCount)
+func formatErrorWithStackTrace(e *Error) string {
+	s := formatErrorMessage(e)
+	for _, loc := range e.StackTrace {
+		s += fmt.Sprintf("    at %v:%v\n", loc.File(), loc.Line())
+	}
+	return strings.TrimSuffix(s, "\n")
+}
+
+func (this *defaultPrintFormat) PrintSummary(summary Summary) {
+	color := green
+	if summary.FailCount > 0 {
+		color = red
+	}
+	fmt.Fprintf(this.out, "\n%v\n", this.color(color, formatSpecCounts(summary)))
+	printSeed(this.out, summary)
+	printSlowest(this.out, "Slowest specs", summary.SlowestSpecs)
+	printSlowest(this.out, "Slowest root specs", summary.SlowestRoots)
+}
+
+// The color is set separately on every line, so that it will not leak to
+// the following lines, even if the output is filtered line by line.
+func (this *defaultPrintFormat) color(color string, s string) string {
+	if !this.colors {
+		return s
+	}
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = color + line + resetColor
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func formatSpecCounts(summary Summary) string {
+	s := fmt.Sprintf("%v specs, %v failures", summary.TotalCount(), summary.FailCount)
+	if summary.SkipCount > 0 {
+		s += fmt.Sprintf(", %v skipped", summary.SkipCount)
+	}
+	if summary.PendingCount > 0 {
+		s += fmt.Sprintf(", %v pending", summary.PendingCount)
+	}
+	if summary.AbortCount > 0 {
+		s += fmt.Sprintf(", %v aborted", summary.AbortCount)
+	}
+	if summary.NotRunCount > 0 {
+		s += fmt.Sprintf(", %v not run", summary.NotRunCount)
+	}
+	if summary.FlakyCount > 0 {
+		s += fmt.Sprintf(", %v flaky", summary.FlakyCount)
+	}
+	return s
+}
+
+func printSlowest(out io.Writer, title string, timings []SpecTiming) {
+	if len(timings) == 0 {
+		return
+	}
+	fmt.Fprintf(out, "\n%v:\n", title)
+	for _, timing := range timings {
+		fmt.Fprintf(out, "%9.3fs  %v\n", timing.Duration.Seconds(), timing.Name)
+	}
+}
+
+func printSeed(out io.Writer, summary Summary) {
+	if summary.Shuffled {
+		fmt.Fprintf(out, "Randomized with seed %v\n", summary.Seed)
+	}
 }
 
 // PrintFormat for use in only tests. Does not print line numbers, colors or
@@ -98,16 +193,20 @@ func (this *simplePrintFormat) PrintFailing(nestingLevel int, name string, error
 	}
 }
 
+func (this *simplePrintFormat) PrintSkipped(nestingLevel int, name string, status Status, reason string) {
+	fmt.Fprintf(this.out, "%v- %v %v\n", indent(nestingLevel), name, statusLabel(status, reason))
+}
+
 func (this *simplePrintFormat) printError(error *Error) {
-	fmt.Fprintf(this.out, formatErrorMessage(error))
+	fmt.Fprint(this.out, formatErrorMessage(error))
 	for _, loc := range error.StackTrace {
 		fmt.Fprintf(this.out, "    at %v\n", loc.FileName())
 	}
 }
 
-func (this *simplePrintFormat) PrintSummary(passCount int, failCount int) {
-	totalCount := passCount + failCount
-	fmt.Fprintf(this.out, "\n%v specs, %v failures\n", totalCount, failCount)
+func (this *simplePrintFormat) PrintSummary(summary Summary) {
+	fmt.Fprintf(this.out, "\n%v\n", formatSpecCounts(summary))
+	printSeed(this.out, summary)
 }
 
 func indent(level int) string {
@@ -46,26 +46,42 @@ func (this *Printer) ShowSummary() {
 	this.showSummary = true
 }
 
-func (this *Printer) VisitSpec(nestingLevel int, name string, errors []*Error) {
-	isPassing := len(errors) == 0
-	isFailing := !isPassing
-
-	if isPassing {
+func (this *Printer) VisitSpec(nestingLevel int, name string, status Status, errors []*Error) {
+	switch status {
+	case Passed:
 		if this.show == ALL {
 			this.format.PrintPassing(nestingLevel, name)
 		} else {
 			this.saveNotPrinted(nestingLevel, name)
 		}
-	}
-	if isFailing {
+	case Failed:
 		this.printNotPrintedParents(nestingLevel)
 		this.format.PrintFailing(nestingLevel, name, errors)
+	case Skipped, Pending, NotRun:
+		if this.show == ALL {
+			this.format.PrintSkipped(nestingLevel, name, status, skipReason(errors))
+		}
+	case Aborted, Flaky:
+		// Specs which were not executed due to a failed assumption, and
+		// specs which passed only when retried, are shown, so that they
+		// would not go unnoticed.
+		this.printNotPrintedParents(nestingLevel)
+		this.format.PrintSkipped(nestingLevel, name, status, "")
+	}
+}
+
+func skipReason(errors []*Error) string {
+	for _, error := range errors {
+		if error.Type == SkipRequested {
+			return error.Message
+		}
 	}
+	return ""
 }
 
-func (this *Printer) VisitEnd(passCount int, failCount int) {
+func (this *Printer) VisitEnd(summary Summary) {
 	if this.showSummary {
-		this.format.PrintSummary(passCount, failCount)
+		this.format.PrintSummary(summary)
 	}
 }
 
@@ -23,10 +23,10 @@ func PrinterSpec(c nanospec.Context) {
 		p.ShowSummary()
 
 		c.Specify("then the summary is printed", func() {
-			p.VisitSpec(0, "Passing 1", noErrors)
-			p.VisitSpec(0, "Passing 2", noErrors)
-			p.VisitSpec(0, "Failing", someError)
-			p.VisitEnd(2, 1)
+			p.VisitSpec(0, "Passing 1", Passed, noErrors)
+			p.VisitSpec(0, "Passing 2", Passed, noErrors)
+			p.VisitSpec(0, "Failing", Failed, someError)
+			p.VisitEnd(Summary{PassCount: 2, FailCount: 1})
 			c.Expect(trim(out.String())).Equals(trim(`
 - Passing 1
 - Passing 2
@@ -34,6 +34,37 @@ func PrinterSpec(c nanospec.Context) {
 *** some error
 
 3 specs, 1 failures
+`))
+		})
+		c.Specify("then the skipped and pending specs are counted, if there are any", func() {
+			p.VisitEnd(Summary{PassCount: 2, FailCount: 1, SkipCount: 3, PendingCount: 4})
+			c.Expect(trim(out.String())).Equals(trim(`
+10 specs, 1 failures, 3 skipped, 4 pending
+`))
+		})
+		c.Specify("then the aborted specs are counted, if there are any", func() {
+			p.VisitEnd(Summary{PassCount: 2, FailCount: 1, AbortCount: 3})
+			c.Expect(trim(out.String())).Equals(trim(`
+6 specs, 1 failures, 3 aborted
+`))
+		})
+		c.Specify("then the specs which were not run are counted, if there are any", func() {
+			p.VisitEnd(Summary{PassCount: 2, FailCount: 1, NotRunCount: 3})
+			c.Expect(trim(out.String())).Equals(trim(`
+6 specs, 1 failures, 3 not run
+`))
+		})
+		c.Specify("then the flaky specs are counted, if there are any", func() {
+			p.VisitEnd(Summary{PassCount: 2, FailCount: 1, FlakyCount: 3})
+			c.Expect(trim(out.String())).Equals(trim(`
+6 specs, 1 failures, 3 flaky
+`))
+		})
+		c.Specify("then the seed is printed, if the specs were shuffled", func() {
+			p.VisitEnd(Summary{PassCount: 2, FailCount: 1, Shuffled: true, Seed: 42})
+			c.Expect(trim(out.String())).Equals(trim(`
+3 specs, 1 failures
+Randomized with seed 42
 `))
 		})
 	})
@@ -42,10 +73,10 @@ func PrinterSpec(c nanospec.Context) {
 		p.HideSummary()
 
 		c.Specify("then the summary is not printed", func() {
-			p.VisitSpec(0, "Passing 1", noErrors)
-			p.VisitSpec(0, "Passing 2", noErrors)
-			p.VisitSpec(0, "Failing", someError)
-			p.VisitEnd(2, 1)
+			p.VisitSpec(0, "Passing 1", Passed, noErrors)
+			p.VisitSpec(0, "Passing 2", Passed, noErrors)
+			p.VisitSpec(0, "Failing", Failed, someError)
+			p.VisitEnd(Summary{PassCount: 2, FailCount: 1})
 			c.Expect(trim(out.String())).Equals(trim(`
 - Passing 1
 - Passing 2
@@ -59,12 +90,30 @@ func PrinterSpec(c nanospec.Context) {
 		p.ShowAll()
 
 		c.Specify("then passing and failing specs are printed", func() {
-			p.VisitSpec(0, "Passing", noErrors)
-			p.VisitSpec(0, "Failing", someError)
+			p.VisitSpec(0, "Passing", Passed, noErrors)
+			p.VisitSpec(0, "Failing", Failed, someError)
 			c.Expect(trim(out.String())).Equals(trim(`
 - Passing
 - Failing [FAIL]
 *** some error
+`))
+		})
+		c.Specify("then the reason for skipping a spec is printed", func() {
+			p.VisitSpec(0, "Skipped", Skipped, []*Error{newError(SkipRequested, "some reason", "", []*Location{})})
+			c.Expect(trim(out.String())).Equals(trim(`
+- Skipped [SKIP: some reason]
+`))
+		})
+		c.Specify("then skipped, pending and aborted specs are printed with their status", func() {
+			p.VisitSpec(0, "Skipped", Skipped, noErrors)
+			p.VisitSpec(0, "Pending", Pending, noErrors)
+			p.VisitSpec(0, "Aborted", Aborted, noErrors)
+			p.VisitSpec(0, "Not run", NotRun, noErrors)
+			c.Expect(trim(out.String())).Equals(trim(`
+- Skipped [SKIP]
+- Pending [PENDING]
+- Aborted [ABORTED]
+- Not run [NOT RUN]
 `))
 		})
 	})
@@ -72,17 +121,44 @@ func PrinterSpec(c nanospec.Context) {
 		p.ShowOnlyFailing()
 
 		c.Specify("then only failing specs are printed", func() {
-			p.VisitSpec(0, "Passing", noErrors)
-			p.VisitSpec(0, "Failing", someError)
+			p.VisitSpec(0, "Passing", Passed, noErrors)
+			p.VisitSpec(0, "Failing", Failed, someError)
 			c.Expect(trim(out.String())).Equals(trim(`
 - Failing [FAIL]
 *** some error
 `))
 		})
 
+		c.Specify("then skipped and pending specs are not printed", func() {
+			p.VisitSpec(0, "Skipped", Skipped, noErrors)
+			p.VisitSpec(0, "Pending", Pending, noErrors)
+			c.Expect(trim(out.String())).Equals("")
+		})
+
+		c.Specify("then aborted specs and their parents are printed", func() {
+			p.VisitSpec(0, "Failing parent", Failed, someError)
+			p.VisitSpec(1, "Passing child", Passed, noErrors)
+			p.VisitSpec(2, "Aborted grandchild", Aborted, noErrors)
+			c.Expect(trim(out.String())).Equals(trim(`
+- Failing parent [FAIL]
+*** some error
+  - Passing child
+    - Aborted grandchild [ABORTED]
+`))
+		})
+
+		c.Specify("then flaky specs and their parents are printed", func() {
+			p.VisitSpec(0, "Passing parent", Passed, noErrors)
+			p.VisitSpec(1, "Flaky child", Flaky, noErrors)
+			c.Expect(trim(out.String())).Equals(trim(`
+- Passing parent
+  - Flaky child [FLAKY]
+`))
+		})
+
 		c.Specify("then the parents of failing specs are printed", func() {
-			p.VisitSpec(0, "Passing parent", noErrors)
-			p.VisitSpec(1, "Failing child", someError)
+			p.VisitSpec(0, "Passing parent", Passed, noErrors)
+			p.VisitSpec(1, "Failing child", Failed, someError)
 			c.Expect(trim(out.String())).Equals(trim(`
 - Passing parent
   - Failing child [FAIL]
@@ -91,9 +167,9 @@ func PrinterSpec(c nanospec.Context) {
 		})
 
 		c.Specify("Case: passing parent with many failing children; should print the parent only once", func() {
-			p.VisitSpec(0, "Passing parent", noErrors)
-			p.VisitSpec(1, "Failing child A", someError)
-			p.VisitSpec(1, "Failing child B", someError)
+			p.VisitSpec(0, "Passing parent", Passed, noErrors)
+			p.VisitSpec(1, "Failing child A", Failed, someError)
+			p.VisitSpec(1, "Failing child B", Failed, someError)
 			c.Expect(trim(out.String())).Equals(trim(`
 - Passing parent
   - Failing child A [FAIL]
@@ -104,9 +180,9 @@ func PrinterSpec(c nanospec.Context) {
 		})
 
 		c.Specify("Case: failing parent with a failing grandchild; should print the child in the middle", func() {
-			p.VisitSpec(0, "Failing parent", someError)
-			p.VisitSpec(1, "Passing child", noErrors)
-			p.VisitSpec(2, "Failing grandchild", someError)
+			p.VisitSpec(0, "Failing parent", Failed, someError)
+			p.VisitSpec(1, "Passing child", Passed, noErrors)
+			p.VisitSpec(2, "Failing grandchild", Failed, someError)
 			c.Expect(trim(out.String())).Equals(trim(`
 - Failing parent [FAIL]
 *** some error
@@ -117,11 +193,11 @@ func PrinterSpec(c nanospec.Context) {
 		})
 
 		c.Specify("Case: failing parent and ghosts of unrelated specs; should not print unrelated specs", func() {
-			p.VisitSpec(0, "Don't show me 0", noErrors)
-			p.VisitSpec(1, "Don't show me 1", noErrors)
-			p.VisitSpec(2, "Don't show me 2", noErrors)
-			p.VisitSpec(0, "Failing parent", someError)
-			p.VisitSpec(1, "Failing child", someError)
+			p.VisitSpec(0, "Don't show me 0", Passed, noErrors)
+			p.VisitSpec(1, "Don't show me 1", Passed, noErrors)
+			p.VisitSpec(2, "Don't show me 2", Passed, noErrors)
+			p.VisitSpec(0, "Failing parent", Failed, someError)
+			p.VisitSpec(1, "Failing child", Failed, someError)
 			c.Expect(trim(out.String())).Equals(trim(`
 - Failing parent [FAIL]
 *** some error
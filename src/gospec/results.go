@@ -8,20 +8,27 @@ import (
 	"container/list"
 	"fmt"
 	"sort"
+	"time"
 )
 
 // Collects test results for all specs in a reporting friendly format.
 type ResultCollector struct {
-	rootsByName map[string]*specResult
-	passCount   int
-	failCount   int
+	rootsByName  map[string]*specResult
+	counts       map[Status]int
+	shuffled     bool
+	seed         int64
+	slowestCount int
 }
 
-func newResultCollector() *ResultCollector {
+// Creates an empty ResultCollector, for example for merging
+// the results of multiple Runners into it.
+func NewResultCollector() *ResultCollector {
 	return &ResultCollector{
 		make(map[string]*specResult),
-		-1,
-		-1,
+		nil,
+		false,
+		0,
+		0,
 	}
 }
 
@@ -44,21 +51,26 @@ func (r *ResultCollector) getOrCreateRoot(spec *specRun) *specResult {
 // Number of specs
 
 func (r *ResultCollector) TotalCount() int {
-	return r.PassCount() + r.FailCount()
-}
-
-func (r *ResultCollector) PassCount() int {
-	if r.passCount < 0 {
-		r.calculateSpecCount()
+	total := 0
+	for _, count := range r.specCounts() {
+		total += count
 	}
-	return r.passCount
+	return total
 }
 
-func (r *ResultCollector) FailCount() int {
-	if r.failCount < 0 {
+func (r *ResultCollector) PassCount() int    { return r.specCounts()[Passed] }
+func (r *ResultCollector) FailCount() int    { return r.specCounts()[Failed] }
+func (r *ResultCollector) SkipCount() int    { return r.specCounts()[Skipped] }
+func (r *ResultCollector) PendingCount() int { return r.specCounts()[Pending] }
+func (r *ResultCollector) AbortCount() int   { return r.specCounts()[Aborted] }
+func (r *ResultCollector) NotRunCount() int  { return r.specCounts()[NotRun] }
+func (r *ResultCollector) FlakyCount() int   { return r.specCounts()[Flaky] }
+
+func (r *ResultCollector) specCounts() map[Status]int {
+	if r.counts == nil {
 		r.calculateSpecCount()
 	}
-	return r.failCount
+	return r.counts
 }
 
 func (r *ResultCollector) calculateSpecCount() {
@@ -69,32 +81,126 @@ func (r *ResultCollector) calculateSpecCount() {
 }
 
 func (r *ResultCollector) resetSpecCount() {
-	r.failCount = 0
-	r.passCount = 0
+	r.counts = make(map[Status]int)
 }
 
 func (r *ResultCollector) incrementSpecCount(spec *specResult) {
-	if spec.isFailed() {
-		r.failCount++
-	} else {
-		r.passCount++
+	r.counts[spec.status()]++
+}
+
+// Adds the results of another ResultCollector to these results, for
+// example the results of another package. The results of the specs
+// which are in both are merged. The other results are not changed.
+func (r *ResultCollector) Merge(other *ResultCollector) {
+	for name, otherRoot := range other.rootsByName {
+		if root, contains := r.rootsByName[name]; contains {
+			root.merge(otherRoot)
+		} else {
+			r.rootsByName[name] = otherRoot.copy()
+		}
+	}
+	r.shuffled = r.shuffled || other.shuffled
+	if other.shuffled {
+		r.seed = other.seed
+	}
+	r.counts = nil
+}
+
+// Makes the summary list the n slowest specs without children,
+// and the n slowest root specs.
+func (r *ResultCollector) ReportSlowest(n int) {
+	r.slowestCount = n
+}
+
+func (r *ResultCollector) slowest() (specs []SpecTiming, roots []SpecTiming) {
+	for root := range r.sortedRoots() {
+		specs = root.appendLeafTimings(specs, "")
+		roots = append(roots, SpecTiming{root.name, root.duration})
+	}
+	return slowestTimings(specs, r.slowestCount), slowestTimings(roots, r.slowestCount)
+}
+
+func slowestTimings(timings []SpecTiming, n int) []SpecTiming {
+	sort.SliceStable(timings, func(i, j int) bool {
+		return timings[i].Duration > timings[j].Duration
+	})
+	if len(timings) > n {
+		timings = timings[:n]
+	}
+	return timings
+}
+
+// Focused specs
+
+// Tells whether some specs were declared with FSpecify.
+func (r *ResultCollector) HasFocusedSpecs() bool {
+	for _, root := range r.rootsByName {
+		if root.containsFocusedSpecs() {
+			return true
+		}
 	}
+	return false
 }
 
 // Visiting the results
 
 type ResultVisitor interface {
-	VisitSpec(nestingLevel int, name string, errors []*Error)
-	VisitEnd(passCount int, failCount int)
+	VisitSpec(nestingLevel int, name string, status Status, errors []*Error)
+	VisitEnd(summary Summary)
+}
+
+// Summary of all the specs, as reported at the end of visiting the results.
+type Summary struct {
+	PassCount    int
+	FailCount    int
+	SkipCount    int
+	PendingCount int
+	AbortCount   int
+	NotRunCount  int
+	FlakyCount   int
+	Shuffled     bool         // whether the specs were executed in random order
+	Seed         int64        // the seed for replaying the random order
+	SlowestSpecs []SpecTiming // the slowest specs without children, if requested with ReportSlowest
+	SlowestRoots []SpecTiming // the slowest root specs, if requested with ReportSlowest
+}
+
+// How long it took to execute a spec. The duration of a spec includes its
+// children and all the runs of the spec, because the parent specs are
+// executed again for each of their children.
+type SpecTiming struct {
+	Name     string // names of the spec and its parents, separated by " / "
+	Duration time.Duration
+}
+
+func (s Summary) TotalCount() int {
+	return s.PassCount + s.FailCount + s.SkipCount + s.PendingCount + s.AbortCount + s.NotRunCount + s.FlakyCount
 }
 
 func (r *ResultCollector) Visit(visitor ResultVisitor) {
 	r.resetSpecCount()
 	r.visitAll(func(spec *specResult) {
 		r.incrementSpecCount(spec)
-		visitor.VisitSpec(len(spec.path), spec.name, listToErrorArray(spec.errors))
+		visitor.VisitSpec(len(spec.path), spec.name, spec.status(), listToErrorArray(spec.errors))
 	})
-	visitor.VisitEnd(r.passCount, r.failCount)
+	visitor.VisitEnd(r.summary())
+}
+
+func (r *ResultCollector) summary() Summary {
+	summary := Summary{
+		PassCount:    r.counts[Passed],
+		FailCount:    r.counts[Failed],
+		SkipCount:    r.counts[Skipped],
+		PendingCount: r.counts[Pending],
+		AbortCount:   r.counts[Aborted],
+		NotRunCount:  r.counts[NotRun],
+		FlakyCount:   r.counts[Flaky],
+		Shuffled:     r.shuffled,
+		Seed:         r.seed,
+	}
+	if r.slowestCount > 0 {
+		summary.SlowestSpecs, summary.SlowestRoots = r.slowest()
+	}
+	return summary
 }
 
 func listToErrorArray(list *list.List) []*Error {
@@ -141,6 +247,9 @@ type specResult struct {
 	path     path
 	children *list.List
 	errors   *list.List
+	focused  bool
+	declared Status
+	duration time.Duration // of all the runs of the spec, including its children
 }
 
 func newSpecResult(spec *specRun) *specResult {
@@ -150,11 +259,61 @@ func newSpecResult(spec *specRun) *specResult {
 		spec.path,
 		list.New(),
 		list.New(),
+		spec.focused,
+		spec.status,
+		0,
 	}
 }
 
-func (this *specResult) isFailed() bool {
-	return this.errors.Len() > 0
+func (this *specResult) status() Status {
+	switch {
+	case this.hasFailures():
+		return Failed
+	case this.hasErrorOfType(SkipRequested):
+		return Skipped
+	}
+	return this.declared
+}
+
+// The reason given to Context.Skip is not a failure.
+func (this *specResult) hasFailures() bool {
+	for e := this.errors.Front(); e != nil; e = e.Next() {
+		if e.Value.(*Error).Type != SkipRequested {
+			return true
+		}
+	}
+	return false
+}
+
+func (this *specResult) hasErrorOfType(errortype ErrorType) bool {
+	for e := this.errors.Front(); e != nil; e = e.Next() {
+		if e.Value.(*Error).Type == errortype {
+			return true
+		}
+	}
+	return false
+}
+
+func (this *specResult) containsFocusedSpecs() bool {
+	found := false
+	this.visitAll(func(spec *specResult) {
+		found = found || spec.focused
+	})
+	return found
+}
+
+func (this *specResult) appendLeafTimings(timings []SpecTiming, parentName string) []SpecTiming {
+	name := this.name
+	if parentName != "" {
+		name = parentName + " / " + this.name
+	}
+	if this.children.Len() == 0 {
+		return append(timings, SpecTiming{name, this.duration})
+	}
+	for e := this.children.Front(); e != nil; e = e.Next() {
+		timings = e.Value.(*specResult).appendLeafTimings(timings, name)
+	}
+	return timings
 }
 
 func (this *specResult) visitAll(visitor func(*specResult)) {
@@ -172,6 +331,8 @@ func (this *specResult) update(spec *specRun) {
 
 	if isMe {
 		this.mergeErrors(spec.errors)
+		this.mergeStatus(spec)
+		this.duration += spec.duration
 	}
 	if isMyDirectChild {
 		if !this.isRegisteredChild(spec) {
@@ -184,6 +345,21 @@ func (this *specResult) update(spec *specRun) {
 	}
 }
 
+func (this *specResult) mergeStatus(spec *specRun) {
+	this.focused = this.focused || spec.focused
+	this.mergeDeclared(spec.status)
+}
+
+// If the spec was executed on any of the runs, then it's not reported
+// as aborted or not run, even if it was not executed on some other run.
+// A parent spec which passed on some runs, but was retried on another,
+// is reported as flaky.
+func (this *specResult) mergeDeclared(status Status) {
+	if this.declared == Aborted || this.declared == NotRun || (this.declared == Passed && status == Flaky) {
+		this.declared = status
+	}
+}
+
 func (this *specResult) mergeErrors(newErrors *list.List) {
 	for e := newErrors.Front(); e != nil; e = e.Next() {
 		error := e.Value.(*Error)
@@ -217,7 +393,10 @@ func (this *specResult) isRegisteredChild(spec *specRun) bool {
 }
 
 func (this *specResult) registerChild(spec *specRun) {
-	newChild := newSpecResult(spec)
+	this.insertChild(newSpecResult(spec))
+}
+
+func (this *specResult) insertChild(newChild *specResult) {
 	pos := this.findFirstChildWithGreaterIndex(newChild.path.lastIndex())
 	if pos != nil {
 		this.children.InsertBefore(newChild, pos)
@@ -246,6 +425,41 @@ func (this *specResult) findChildOnPath(targetPath path) *specResult {
 	return nil
 }
 
+// Merges the results of the same spec from another ResultCollector.
+func (this *specResult) merge(other *specResult) {
+	this.mergeErrors(other.errors)
+	this.focused = this.focused || other.focused
+	this.mergeDeclared(other.declared)
+	if other.duration > this.duration {
+		this.duration = other.duration
+	}
+	for e := other.children.Front(); e != nil; e = e.Next() {
+		otherChild := e.Value.(*specResult)
+		if child := this.findChildOnPath(otherChild.path); child != nil {
+			child.merge(otherChild)
+		} else {
+			this.insertChild(otherChild.copy())
+		}
+	}
+}
+
+func (this *specResult) copy() *specResult {
+	result := &specResult{
+		this.name,
+		this.path,
+		list.New(),
+		list.New(),
+		this.focused,
+		this.declared,
+		this.duration,
+	}
+	result.errors.PushBackList(this.errors)
+	for e := this.children.Front(); e != nil; e = e.Next() {
+		result.children.PushBack(e.Value.(*specResult).copy())
+	}
+	return result
+}
+
 func (this *specResult) String() string {
 	return fmt.Sprintf("%T{%v, %v, %d children, %d errors}",
 		this, this.name, this.path, this.children.Len(), this.errors.Len())
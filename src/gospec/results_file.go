@@ -0,0 +1,155 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// The results are saved as JSON, so that the gospec command can combine
+// the results of multiple packages, each of which is executed in its own
+// process. The statuses and error types are saved by their names. The
+// format is documented in the README, and its version is incremented
+// whenever it is changed so that old readers would misread it.
+const resultsFormatVersion = 1
+
+type savedResults struct {
+	Version  int          `json:"version"`
+	Shuffled bool         `json:"shuffled"`
+	Seed     int64        `json:"seed"`
+	Specs    []*savedSpec `json:"specs"`
+}
+
+type savedSpec struct {
+	Name     string        `json:"name"`
+	Path     []int         `json:"path"`
+	Status   string        `json:"status"`
+	Focused  bool          `json:"focused,omitempty"`
+	Duration float64       `json:"duration,omitempty"` // in seconds
+	Errors   []*savedError `json:"errors,omitempty"`
+	Children []*savedSpec  `json:"children,omitempty"`
+}
+
+type savedError struct {
+	Type       string           `json:"type"`
+	Message    string           `json:"message"`
+	Actual     string           `json:"actual,omitempty"`
+	StackTrace []*savedLocation `json:"stackTrace,omitempty"`
+}
+
+type savedLocation struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+// Writes the results as JSON, in a form which can be read with LoadResults.
+func (r *ResultCollector) Save(out io.Writer) error {
+	saved := &savedResults{resultsFormatVersion, r.shuffled, r.seed, []*savedSpec{}}
+	for root := range r.sortedRoots() {
+		saved.Specs = append(saved.Specs, saveSpec(root))
+	}
+	return json.NewEncoder(out).Encode(saved)
+}
+
+func saveSpec(spec *specResult) *savedSpec {
+	saved := &savedSpec{spec.name, spec.path, spec.status().String(), spec.focused, spec.duration.Seconds(), nil, nil}
+	for e := spec.errors.Front(); e != nil; e = e.Next() {
+		saved.Errors = append(saved.Errors, saveError(e.Value.(*Error)))
+	}
+	for e := spec.children.Front(); e != nil; e = e.Next() {
+		saved.Children = append(saved.Children, saveSpec(e.Value.(*specResult)))
+	}
+	return saved
+}
+
+func saveError(error *Error) *savedError {
+	saved := &savedError{error.Type.String(), error.Message, error.Actual, nil}
+	for _, loc := range error.StackTrace {
+		saved.StackTrace = append(saved.StackTrace, &savedLocation{loc.Name(), loc.File(), loc.Line()})
+	}
+	return saved
+}
+
+// Reads results which were written with ResultCollector.Save.
+func LoadResults(in io.Reader) (*ResultCollector, error) {
+	saved := &savedResults{}
+	if err := json.NewDecoder(in).Decode(saved); err != nil {
+		return nil, err
+	}
+	if saved.Version > resultsFormatVersion {
+		return nil, fmt.Errorf("unsupported results format version: %v", saved.Version)
+	}
+	r := NewResultCollector()
+	r.shuffled = saved.Shuffled
+	r.seed = saved.Seed
+	for _, savedRoot := range saved.Specs {
+		root, err := loadSpec(savedRoot)
+		if err != nil {
+			return nil, err
+		}
+		r.rootsByName[root.name] = root
+	}
+	return r, nil
+}
+
+func loadSpec(saved *savedSpec) (*specResult, error) {
+	status, err := parseStatus(saved.Status)
+	if err != nil {
+		return nil, err
+	}
+	duration := time.Duration(math.Round(saved.Duration * float64(time.Second)))
+	spec := &specResult{saved.Name, saved.Path, list.New(), list.New(), saved.Focused, status, duration}
+	for _, savedError := range saved.Errors {
+		error, err := loadError(savedError)
+		if err != nil {
+			return nil, err
+		}
+		spec.addError(error)
+	}
+	for _, savedChild := range saved.Children {
+		child, err := loadSpec(savedChild)
+		if err != nil {
+			return nil, err
+		}
+		spec.insertChild(child)
+	}
+	return spec, nil
+}
+
+func loadError(saved *savedError) (*Error, error) {
+	errortype, err := parseErrorType(saved.Type)
+	if err != nil {
+		return nil, err
+	}
+	stacktrace := []*Location{}
+	for _, loc := range saved.StackTrace {
+		stacktrace = append(stacktrace, &Location{loc.Function, loc.File, loc.Line})
+	}
+	return newError(errortype, saved.Message, saved.Actual, stacktrace), nil
+}
+
+func parseStatus(name string) (Status, error) {
+	for status := Passed; status <= Flaky; status++ {
+		if status.String() == name {
+			return status, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown status: %v", name)
+}
+
+func parseErrorType(name string) (ErrorType, error) {
+	for errortype := ExpectFailed; errortype <= SkipRequested; errortype++ {
+		if errortype.String() == name {
+			return errortype, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown error type: %v", name)
+}
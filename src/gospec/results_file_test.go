@@ -0,0 +1,151 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"bytes"
+	"github.com/orfjackal/nanospec.go/src/nanospec"
+)
+
+func saveAndLoad(results *ResultCollector) *ResultCollector {
+	buf := new(bytes.Buffer)
+	if err := results.Save(buf); err != nil {
+		panic(err)
+	}
+	loaded, err := LoadResults(buf)
+	if err != nil {
+		panic(err)
+	}
+	return loaded
+}
+
+func ResultsFileSpec(c nanospec.Context) {
+
+	c.Specify("Saved results can be loaded", func() {
+		results := runSpec(func(c Context) {
+			c.Specify("Passing", func() {})
+			c.Specify("Failing", func() {
+				c.Expect(1, Equals, 2)
+			})
+			c.XSpecify("Skipped", func() {})
+			c.Specify("Skipped with a reason", func() {
+				c.Skip("some reason")
+			})
+			c.Pending("Pending")
+		})
+		c.Expect(saveAndLoad(results)).Matches(ReportIs(`
+- RootSpec
+  - Passing
+  - Failing [FAIL]
+*** Expected: equals “2”
+         got: “1”
+    at results_file_test.go
+  - Skipped [SKIP]
+  - Skipped with a reason [SKIP: some reason]
+  - Pending [PENDING]
+
+6 specs, 1 failures, 2 skipped, 1 pending
+`))
+	})
+
+	c.Specify("Saved results remember the seed of the random order", func() {
+		runner := NewRunner()
+		runner.Shuffle(42)
+		runner.AddNamedSpec("RootSpec", DummySpecWithNoChildren)
+		runner.Run()
+		c.Expect(saveAndLoad(runner.Results())).Matches(ReportContains("Randomized with seed 42"))
+	})
+
+	c.Specify("Saved results remember the focused specs", func() {
+		results := runSpec(func(c Context) {
+			c.FSpecify("Focused", func() {})
+			c.Specify("Unrelated", func() {
+				c.Expect(1, Equals, 2)
+			})
+		})
+		loaded := saveAndLoad(results)
+		c.Expect(loaded.HasFocusedSpecs()).IsTrue()
+		c.Expect(loaded.SkipCount()).Equals(1)
+		c.Expect(loaded.FailCount()).Equals(0)
+	})
+
+	c.Specify("Invalid results are reported as errors", func() {
+		_, err := LoadResults(bytes.NewBufferString(`{"specs": [{"name": "RootSpec", "status": "bogus"}]}`))
+		c.Expect(err).Satisfies(err != nil)
+	})
+
+	c.Specify("Saved results have the version of the format", func() {
+		buf := new(bytes.Buffer)
+		runSpec(DummySpecWithNoChildren).Save(buf)
+		c.Expect(bytes.HasPrefix(buf.Bytes(), []byte(`{"version":1,`))).IsTrue()
+	})
+
+	c.Specify("Results of a newer version of the format are reported as errors", func() {
+		_, err := LoadResults(bytes.NewBufferString(`{"version": 2, "specs": []}`))
+		c.Expect(err).Satisfies(err != nil)
+	})
+
+	c.Specify("When merging results", func() {
+		first := runSpec(func(c Context) {
+			c.Specify("Child A", func() {})
+		})
+
+		c.Specify("the root specs of both results are included", func() {
+			second := NewResultCollector()
+			second.Update(newSpecRun("OtherSpec", nil, nil, nil))
+			first.Merge(second)
+			c.Expect(first).Matches(ReportIs(`
+- OtherSpec
+- RootSpec
+  - Child A
+
+3 specs, 0 failures
+`))
+		})
+		c.Specify("the children of the same root spec are merged", func() {
+			root := newSpecRun("RootSpec", nil, nil, nil)
+			newSpecRun("Child A", nil, root, nil)
+			childB := newSpecRun("Child B", nil, root, nil)
+			childB.AddError(newError(OtherError, "some error", "", []*Location{}))
+			second := NewResultCollector()
+			second.Update(root)
+			second.Update(childB)
+			first.Merge(second)
+			c.Expect(first).Matches(ReportIs(`
+- RootSpec
+  - Child A
+  - Child B [FAIL]
+*** some error
+
+3 specs, 1 failures
+`))
+		})
+		c.Specify("the merged results are not changed", func() {
+			second := NewResultCollector()
+			second.Update(newSpecRun("OtherSpec", nil, nil, nil))
+			third := NewResultCollector()
+			otherRoot := newSpecRun("OtherSpec", nil, nil, nil)
+			child := newSpecRun("Child", nil, otherRoot, nil)
+			child.AddError(newError(OtherError, "some error", "", []*Location{}))
+			third.Update(otherRoot)
+			third.Update(child)
+			first.Merge(second)
+			first.Merge(third)
+			c.Expect(second).Matches(ReportIs(`
+- OtherSpec
+
+1 specs, 0 failures
+`))
+			c.Expect(third).Matches(ReportIs(`
+- OtherSpec
+  - Child [FAIL]
+*** some error
+
+2 specs, 1 failures
+`))
+			c.Expect(first.TotalCount()).Equals(4)
+		})
+	})
+}
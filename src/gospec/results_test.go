@@ -12,7 +12,7 @@ import (
 )
 
 func ResultsSpec(c nanospec.Context) {
-	results := newResultCollector()
+	results := NewResultCollector()
 
 	c.Specify("When results have many root specs", func() {
 		results.Update(newSpecRun("RootSpec2", nil, nil, nil))
@@ -64,6 +64,22 @@ func ResultsSpec(c nanospec.Context) {
 		})
 	})
 
+	c.Specify("When the specs were shuffled", func() {
+		runner := NewRunner()
+		runner.Shuffle(42)
+		runner.AddNamedSpec("RootSpec", DummySpecWithNoChildren)
+		runner.Run()
+
+		c.Specify("then the seed is reported", func() {
+			c.Expect(runner.Results()).Matches(ReportIs(`
+- RootSpec
+
+1 specs, 0 failures
+Randomized with seed 42
+`))
+		})
+	})
+
 	c.Specify("Case: zero specs", func() {
 		c.Expect(results).Matches(ReportIs(`
 0 specs, 0 failures
@@ -0,0 +1,150 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"github.com/orfjackal/nanospec.go/src/nanospec"
+)
+
+func RetrySpec(c nanospec.Context) {
+	r := NewRunner()
+	attempts := 0
+	failTimes := func(times int) func(Context) {
+		return func(c Context) {
+			c.Specify("Passing", func() {})
+			c.Specify("Flaky", func() {
+				attempts++
+				c.Expect(attempts > times, IsTrue)
+			})
+		}
+	}
+
+	c.Specify("When a failed spec is retried and it passes", func() {
+		r.SetRetries(2)
+		r.AddNamedSpec("RootSpec", failTimes(1))
+		r.RunSerially()
+		results := r.Results()
+
+		c.Specify("it is reported as flaky", func() {
+			c.Expect(results).Matches(ReportIs(`
+- RootSpec
+  - Passing
+  - Flaky [FLAKY]
+
+3 specs, 0 failures, 1 flaky
+`))
+		})
+		c.Specify("it is retried only until it passes", func() {
+			c.Expect(attempts).Equals(2)
+		})
+	})
+
+	c.Specify("When a failed spec fails also on every retry, it is reported as failed", func() {
+		r.SetRetries(2)
+		r.AddNamedSpec("RootSpec", failTimes(10))
+		r.RunSerially()
+		results := r.Results()
+		c.Expect(attempts).Equals(3)
+		c.Expect(results.FailCount()).Equals(1)
+		c.Expect(results.FlakyCount()).Equals(0)
+		c.Expect(results.TotalCount()).Equals(3)
+	})
+
+	c.Specify("By default the failed specs are not retried", func() {
+		r.AddNamedSpec("RootSpec", failTimes(1))
+		r.RunSerially()
+		c.Expect(attempts).Equals(1)
+		c.Expect(r.Results().FailCount()).Equals(1)
+	})
+
+	c.Specify("The number of retries can be set for a subtree of specs", func() {
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			c.Specify("Retried", func() {
+				c.Retry(1)
+				c.Specify("Flaky child", func() {
+					attempts++
+					c.Expect(attempts > 1, IsTrue)
+				})
+			})
+			c.Specify("Not retried", func() {
+				c.Expect(1, Equals, 2)
+			})
+		})
+		r.RunSerially()
+		results := r.Results()
+		c.Expect(attempts).Equals(2)
+		c.Expect(results.FlakyCount()).Equals(1)
+		c.Expect(results.FailCount()).Equals(1)
+	})
+
+	c.Specify("When a parent spec is retried, its children are executed again", func() {
+		childRuns := 0
+		r.SetRetries(1)
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			c.Specify("Flaky parent", func() {
+				attempts++
+				c.Expect(attempts > 1, IsTrue)
+				c.Specify("Child 1", func() { childRuns++ })
+				c.Specify("Child 2", func() { childRuns++ })
+			})
+		})
+		r.RunSerially()
+		c.Expect(r.Results()).Matches(ReportIs(`
+- RootSpec
+  - Flaky parent [FLAKY]
+    - Child 1
+    - Child 2
+
+4 specs, 0 failures, 1 flaky
+`))
+		c.Expect(childRuns).Equals(3)
+	})
+
+	c.Specify("When a parent spec fails on a later run and is retried", func() {
+		runs := make(map[string]int)
+		r.SetRetries(1)
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			c.Specify("Flaky parent", func() {
+				runs["parent"]++
+				c.Expect(runs["parent"] != 2, IsTrue)
+				c.Specify("Child 1", func() { runs["child 1"]++ })
+				c.Specify("Child 2", func() { runs["child 2"]++ })
+			})
+		})
+		r.RunSerially()
+
+		c.Specify("it is reported as flaky", func() {
+			c.Expect(r.Results()).Matches(ReportIs(`
+- RootSpec
+  - Flaky parent [FLAKY]
+    - Child 1
+    - Child 2
+
+4 specs, 0 failures, 1 flaky
+`))
+		})
+		c.Specify("only the path to the failed child is retried", func() {
+			c.Expect(runs["parent"]).Equals(3)
+			c.Expect(runs["child 1"]).Equals(1)
+			c.Expect(runs["child 2"]).Equals(2)
+		})
+	})
+
+	c.Specify("A failed spec is retried only as many times as allowed, also when it has postponed children", func() {
+		runs := 0
+		r.SetRetries(1)
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			c.Specify("Failing parent", func() {
+				runs++
+				c.Expect(1, Equals, 2)
+				c.Specify("Child 1", func() {})
+				c.Specify("Child 2", func() {})
+			})
+		})
+		r.RunSerially()
+		c.Expect(runs).Equals(3)
+		c.Expect(r.Results().FailCount()).Equals(1)
+	})
+}
@@ -4,24 +4,61 @@
 
 package gospec
 
+import (
+	"container/heap"
+	"fmt"
+	"io"
+	"math/rand"
+	"runtime"
+	"time"
+)
+
 const (
 	channelBufferSize = 10
 )
 
 // Runner executes the specs and collects their results.
 type Runner struct {
-	runningTasks int
-	results      chan *taskResult
-	executed     []*specRun
-	scheduled    []*scheduledTask
+	parallelism   int
+	rootSpecCount int
+	runningTasks  int
+	declaring     int // number of running tasks which declare the children of a root spec
+	results       chan *taskResult
+	executed      []*specRun
+	scheduled     scheduledTasks
+	random        *rand.Rand
+	seed          int64
+	timeout       time.Duration
+	retries       int
+	attempts      map[string]int // how many times each spec has been retried
+	filter        *nameFilter
+	focus         *focusedSpecs
+	rootNames     []string
+	beforeAll     []func() error
+	afterAll      []func() error
+	fixtures      []*sharedFixture
+	timedOut      map[string][]<-chan bool // of the timed out tasks which may still be running, by root name
+	failFast      bool
+	stopped       bool
+	shard         *shard
+	events        *eventStream
 }
 
 func NewRunner() *Runner {
 	r := new(Runner)
+	r.parallelism = runtime.GOMAXPROCS(0)
 	r.runningTasks = 0
 	r.results = make(chan *taskResult, channelBufferSize)
 	r.executed = make([]*specRun, 0)
-	r.scheduled = make([]*scheduledTask, 0)
+	r.scheduled = make(scheduledTasks, 0)
+	r.attempts = make(map[string]int)
+	r.filter = newNameFilter()
+	r.focus = newFocusedSpecs()
+	r.rootNames = make([]string, 0)
+	r.beforeAll = make([]func() error, 0)
+	r.afterAll = make([]func() error, 0)
+	r.fixtures = make([]*sharedFixture, 0)
+	r.timedOut = make(map[string][]<-chan bool)
 	return r
 }
 
@@ -34,24 +71,251 @@ func (r *Runner) AddSpec(closure func(Context)) {
 // Adds a spec for later execution. Uses the provided name instead of
 // retrieving the name of the spec function with reflection.
 func (r *Runner) AddNamedSpec(name string, closure func(Context)) {
-	task := newScheduledTask(name, closure, newInitialContext())
-	r.scheduled = append(r.scheduled, task)
+	order := r.rootSpecCount
+	r.rootSpecCount++
+	r.schedule(newScheduledTask(name, closure, order, newInitialContext()))
+	r.rootNames = append(r.rootNames, name)
+}
+
+// Adds a spec whose closure is given a shared fixture. The fixture is set
+// up only once, when the spec is first executed, and the same value is given
+// to every execution of the spec's closure, so the specs must not modify it.
+// If the fixture implements io.Closer, it is closed after all the specs have
+// been executed. If setting up the fixture fails, the spec fails without its
+// closure being executed. Example:
+//
+//	r.AddSpecWithFixture(StartTestServer, SomeSpec);
+func (r *Runner) AddSpecWithFixture(setup func() (interface{}, error), closure func(Context, interface{})) {
+	r.AddNamedSpecWithFixture(functionName(closure), setup, closure)
+}
+
+// Same as AddSpecWithFixture, but uses the provided name instead of
+// retrieving the name of the spec function with reflection.
+func (r *Runner) AddNamedSpecWithFixture(name string, setup func() (interface{}, error), closure func(Context, interface{})) {
+	fixture := newSharedFixture(name, setup)
+	r.fixtures = append(r.fixtures, fixture)
+	r.AddNamedSpec(name, fixture.wrap(closure))
+}
+
+// Adds a function which is called once before executing any of the specs,
+// for example to start a server which all the specs use. If it returns an
+// error or panics, none of the specs are executed and all of them are
+// reported as failed because of it.
+func (r *Runner) BeforeAll(f func() error) {
+	r.beforeAll = append(r.beforeAll, f)
+}
+
+// Adds a function which is called once after all the specs have been
+// executed, even if BeforeAll failed. The functions are called in reverse
+// order. If it returns an error or panics, all of the specs are reported
+// as failed because of it.
+func (r *Runner) AfterAll(f func() error) {
+	r.afterAll = append(r.afterAll, f)
+}
+
+// Sets the maximum number of specs which are executed concurrently.
+// Defaults to GOMAXPROCS. Values less than 1 are treated as 1.
+func (r *Runner) SetParallelism(n int) {
+	if n < 1 {
+		n = 1
+	}
+	r.parallelism = n
+}
+
+// Sets the default timeout for every spec. A spec times out if it runs for
+// longer than the timeout, measured from when the spec started and including
+// the time spent in its children. A timed out spec is reported as failed and
+// the rest of the specs are executed normally. Zero means no timeout.
+//
+// The goroutine of a timed out spec cannot be stopped, so it keeps running
+// in the background until it calls the Context the next time, or forever
+// if it is blocked. The shared fixtures and AfterAll wait for a while for
+// such goroutines to stop before cleaning up after them.
+// The timeout can be overridden for a subtree of specs with Context.SetTimeout.
+func (r *Runner) SetTimeout(timeout time.Duration) {
+	r.timeout = timeout
+}
+
+// Sets how many times a failed spec is retried. The spec is executed again
+// in a new run, together with its children, and if it then passes, it is
+// reported as flaky instead of as failed. Zero means no retries. The number
+// of retries can be overridden for a subtree of specs with Context.Retry.
+func (r *Runner) SetRetries(retries int) {
+	r.retries = retries
+}
+
+// Executes only the specs whose names match the regular expression. The
+// pattern is matched against the whole nested name of a spec, in which the
+// names of the root spec and its nested specs are separated by slashes. For
+// example "StackSpec/.*empty" matches all specs about an empty stack in
+// StackSpec. The children of the matching specs are executed too. The other
+// specs are executed only for finding their matching children, and they are
+// reported only if they are the parents of some matching spec.
+func (r *Runner) Focus(pattern string) error {
+	return r.filter.setFocus(pattern)
+}
+
+// Does not execute the specs, nor their children, whose names match the
+// pattern. The pattern is interpreted the same way as with Focus.
+func (r *Runner) Exclude(pattern string) error {
+	return r.filter.setSkip(pattern)
+}
+
+// Stops executing new specs after the first spec fails. The specs which are
+// already being executed are allowed to finish, and the specs which were not
+// executed are reported as not run.
+func (r *Runner) FailFast() {
+	r.failFast = true
+}
+
+// Writes the results as newline-delimited JSON events to out, while the
+// specs are executed, so that long runs can be followed. The events are
+// documented in the README.
+func (r *Runner) StreamEvents(out io.Writer) {
+	r.events = newEventStream(out)
+}
+
+// Makes the root specs and the sibling specs to be executed in random order,
+// instead of in declaration order. Using the same seed reproduces the same
+// order, at least when the specs are executed with RunSerially.
+func (r *Runner) Shuffle(seed int64) {
+	r.random = rand.New(rand.NewSource(seed))
+	r.seed = seed
 }
 
 // Executes all the specs which have been added with AddSpec. The specs
-// are executed using as many goroutines as possible, so that even individual
-// spec methods are executed in multiple goroutines.
+// are executed in multiple goroutines, so that even individual spec methods
+// are executed in parallel, but at most as many at a time as has been
+// set with SetParallelism.
 func (r *Runner) Run() {
-	r.startAllScheduledTasks()
-	r.startNewTasksAndWaitUntilFinished()
+	r.selectShard()
+	r.declareRootSpecsFirst()
+	r.events.start()
+	if r.setUpSuite() {
+		r.startAllScheduledTasks()
+		r.startNewTasksAndWaitUntilFinished()
+		r.reportNotRunTasks()
+	}
+	r.tearDownSuite()
+	r.events.end(r.isShuffled(), r.seed)
+}
+
+// Executes all the specs which have been added with AddSpec, one spec
+// at a time in the order in which they were declared. This is slower than
+// Run, but it makes the execution order and output reproducible, which helps
+// when debugging specs that depend on shared state.
+func (r *Runner) RunSerially() {
+	r.selectShard()
+	r.declareRootSpecsFirst()
+	r.events.start()
+	if r.setUpSuite() {
+		for r.hasScheduledTasks() && !r.stopped {
+			r.executeNextScheduledTask()
+		}
+		r.reportNotRunTasks()
+	}
+	r.tearDownSuite()
+	r.events.end(r.isShuffled(), r.seed)
+}
+
+func (r *Runner) setUpSuite() bool {
+	for _, f := range r.beforeAll {
+		if e := callSuiteFunction("BeforeAll", f); e != nil {
+			r.failAllRootSpecs(e)
+			r.scheduled = r.scheduled[:0]
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Runner) tearDownSuite() {
+	for _, fixture := range r.fixtures {
+		waitUntilStopped(r.timedOut[fixture.rootName])
+		if err := fixture.close(); err != nil {
+			message := fmt.Sprintf("Fixture close failed: %v", err)
+			r.failRootSpec(fixture.rootName, newError(OtherError, message, "", []*Location{}))
+		}
+	}
+	if len(r.afterAll) > 0 {
+		for _, stillRunning := range r.timedOut {
+			waitUntilStopped(stillRunning)
+		}
+	}
+	for i := len(r.afterAll) - 1; i >= 0; i-- {
+		if e := callSuiteFunction("AfterAll", r.afterAll[i]); e != nil {
+			r.failAllRootSpecs(e)
+		}
+	}
+}
+
+func (r *Runner) failAllRootSpecs(e *Error) {
+	for _, name := range r.rootNames {
+		r.failRootSpec(name, e)
+	}
+}
+
+func (r *Runner) failRootSpec(name string, e *Error) {
+	spec := newSpecRun(name, nil, nil, rootPath())
+	if !r.filter.isSkipped(spec) {
+		spec.AddFatalError(e)
+		r.addExecuted(spec)
+	}
+}
+
+// Executes only the failed specs, instead of all the specs which have been
+// added to the Runner. Must be called before executing the specs. Fails if
+// none of the root specs of the failed specs have been added, because then
+// nothing would be executed.
+func (r *Runner) rerunFailed(failed []*failedSpec) error {
+	scheduled := make(scheduledTasks, 0)
+	rootNames := make([]string, 0)
+	for _, task := range r.scheduled {
+		found := false
+		for _, spec := range failed {
+			if spec.Names[0] == task.name {
+				context := newRerunContext(spec.Path, spec.Names)
+				scheduled = append(scheduled, newScheduledTask(task.name, task.closure, task.order, context))
+				found = true
+			}
+		}
+		if found {
+			rootNames = append(rootNames, task.name)
+		}
+	}
+	if len(rootNames) == 0 {
+		return fmt.Errorf("none of the root specs of the %v failed specs were found", len(failed))
+	}
+	heap.Init(&scheduled)
+	r.scheduled = scheduled
+	r.rootNames = rootNames
+	return nil
+}
+
+// The root specs are first executed without executing their children, and
+// their children are executed only after all root specs have declared their
+// children, so that it's known which of them are focused with FSpecify,
+// regardless of the order in which the root specs are executed.
+func (r *Runner) declareRootSpecsFirst() {
+	for _, task := range r.scheduled {
+		if task.context.targetPath.isRoot() && task.context.targetNames == nil {
+			task.context.declareOnly = true
+		}
+	}
+	heap.Init(&r.scheduled)
 }
 
 func (r *Runner) startAllScheduledTasks() {
-	for r.hasScheduledTasks() {
+	for r.hasScheduledTasks() && r.hasFreeWorkers() && r.canStartNextTask() && !r.stopped {
 		r.startNextScheduledTask()
 	}
 }
 
+// The declaring tasks are before the other tasks in the scheduled tasks.
+func (r *Runner) canStartNextTask() bool {
+	return r.declaring == 0 || r.scheduled[0].context.declareOnly
+}
+
 func (r *Runner) startNewTasksAndWaitUntilFinished() {
 	for r.hasRunningTasks() {
 		r.processNextFinishedTask()
@@ -59,7 +323,6 @@ func (r *Runner) startNewTasksAndWaitUntilFinished() {
 	}
 }
 
-// For testing purposes, so that the specs can be executed deterministically.
 func (r *Runner) executeNextScheduledTask() {
 	r.startNextScheduledTask()
 	r.processNextFinishedTask()
@@ -67,8 +330,11 @@ func (r *Runner) executeNextScheduledTask() {
 
 func (r *Runner) startNextScheduledTask() {
 	task := r.nextScheduledTask()
+	if task.context.declareOnly {
+		r.declaring++
+	}
 	go func() {
-		r.results <- r.execute(task.name, task.closure, task.context)
+		r.results <- r.executeTask(task)
 	}()
 	r.runningTasks++
 }
@@ -76,38 +342,191 @@ func (r *Runner) startNextScheduledTask() {
 func (r *Runner) processNextFinishedTask() {
 	result := <-r.results
 	r.runningTasks--
+	if result.declareOnly {
+		r.declaring--
+	}
 	r.saveResult(result)
 }
 
+func (r *Runner) hasFreeWorkers() bool    { return r.runningTasks < r.parallelism }
 func (r *Runner) hasRunningTasks() bool   { return r.runningTasks > 0 }
 func (r *Runner) hasScheduledTasks() bool { return len(r.scheduled) > 0 }
 func (r *Runner) nextScheduledTask() *scheduledTask {
-	last := len(r.scheduled) - 1
-	popped := r.scheduled[last]
-	r.scheduled = r.scheduled[:last]
-	return popped
+	if r.isShuffled() {
+		return heap.Remove(&r.scheduled, r.randomScheduledTask()).(*scheduledTask)
+	}
+	return heap.Pop(&r.scheduled).(*scheduledTask)
+}
+
+// While some root specs have not declared their children, only the tasks
+// which declare them may be executed.
+func (r *Runner) randomScheduledTask() int {
+	if !r.scheduled[0].context.declareOnly {
+		return r.random.Intn(len(r.scheduled))
+	}
+	declaring := make([]int, 0)
+	for i, task := range r.scheduled {
+		if task.context.declareOnly {
+			declaring = append(declaring, i)
+		}
+	}
+	return declaring[r.random.Intn(len(declaring))]
+}
+
+func (r *Runner) schedule(task *scheduledTask) {
+	heap.Push(&r.scheduled, task)
+}
+
+func (r *Runner) isShuffled() bool { return r.random != nil }
+
+func (r *Runner) executeTask(task *scheduledTask) *taskResult {
+	if task.spec != nil && r.focus.isUnrelated(task.spec) {
+		return r.skipUnfocused(task)
+	}
+	result := r.execute(task.name, task.closure, task.context)
+	result.order = task.order
+	return result
+}
+
+// The postponed specs which are not related to the focused specs are
+// reported as skipped without executing their parents again. Some specs may
+// have been focused only after the spec was postponed.
+func (r *Runner) skipUnfocused(task *scheduledTask) *taskResult {
+	task.spec.status = Skipped
+	skipped := []*specRun{}
+	if !task.spec.searched {
+		skipped = append(skipped, task.spec)
+	}
+	return &taskResult{
+		task.name,
+		task.closure,
+		task.order,
+		skipped,
+		[]*specRun{},
+		nil,
+		nil,
+		task.context.targetNames,
+		false,
+		nil,
+	}
 }
 
 func (r *Runner) execute(name string, closure specRoot, c *taskContext) *taskResult {
-	c.Specify(name, func() { closure(c) })
+	c.defaultTimeout = r.timeout
+	c.defaultRetries = r.retries
+	c.filter = r.filter
+	c.focus = r.focus
+	stillRunning := c.run(name, func() { closure(c) })
 	return &taskResult{
 		name,
 		closure,
-		asSpecArray(c.executedSpecs),
+		0,
+		c.reportedSpecs(),
 		asSpecArray(c.postponedSpecs),
+		c.continuationAfterTimeout(),
+		c.nameMatchingFallback(),
+		c.targetNames,
+		c.declareOnly,
+		stillRunning,
 	}
 }
 
 func (r *Runner) saveResult(result *taskResult) {
+	if result.declareOnly && len(result.postponedSpecs) > 0 {
+		// the root spec is executed again with each of its children,
+		// which reports its errors together with the errors of the child
+		for _, spec := range result.executedSpecs {
+			if spec.path.isRoot() {
+				spec.clearErrors()
+			}
+		}
+	}
+	if result.stillRunning != nil {
+		r.timedOut[result.name] = append(r.timedOut[result.name], result.stillRunning)
+	}
+	retried := r.retryFailedSpecs(result)
 	for _, spec := range result.executedSpecs {
-		r.executed = append(r.executed, spec)
+		if isRetried(spec, retried) {
+			continue
+		}
+		r.addExecuted(spec)
+		if r.failFast && spec.hasFailures() {
+			r.stopped = true
+		}
 	}
 	for _, spec := range result.postponedSpecs {
-		task := newScheduledTask(result.name, result.closure, newExplicitContext(spec.path))
-		r.scheduled = append(r.scheduled, task)
+		if isRetried(spec, retried) {
+			continue
+		}
+		context := newExplicitContext(spec.path)
+		context.targetNames = result.targetNames
+		task := newScheduledTask(result.name, result.closure, result.order, context)
+		task.spec = spec
+		r.schedule(task)
+	}
+	if result.continuation != nil {
+		task := newScheduledTask(result.name, result.closure, result.order, result.continuation)
+		r.schedule(task)
+	}
+	if result.fallback != nil {
+		task := newScheduledTask(result.name, result.closure, result.order, result.fallback)
+		r.schedule(task)
 	}
 }
 
+// Schedules the failed specs to be executed again, if they have retries
+// left. The results of the failed attempt are replaced by the results of
+// the retry, which executes also the children of the failed spec which were
+// executed on the failed attempt. The attempts are counted per spec, because
+// a spec is executed on many tasks when its children are postponed.
+func (r *Runner) retryFailedSpecs(result *taskResult) []*specRun {
+	retried := make([]*specRun, 0)
+	for _, spec := range result.executedSpecs {
+		key := retryKey(result.name, spec.path)
+		if spec.hasFailures() && r.attempts[key] < spec.retries && !isRetried(spec, retried) {
+			r.attempts[key]++
+			target := spec.path
+			if spec.isOnTargetPath() {
+				target = spec.targetPath
+			}
+			context := newRetryContext(target, spec.path, r.attempts[key])
+			context.targetNames = result.targetNames
+			task := newScheduledTask(result.name, result.closure, result.order, context)
+			task.spec = spec
+			r.schedule(task)
+			retried = append(retried, spec)
+		}
+	}
+	return retried
+}
+
+func (r *Runner) addExecuted(spec *specRun) {
+	r.executed = append(r.executed, spec)
+	r.events.specExecuted(spec)
+}
+
+func isRetried(spec *specRun, retried []*specRun) bool {
+	for _, other := range retried {
+		if other.path.isOn(spec.path) {
+			return true
+		}
+	}
+	return false
+}
+
+// The specs which were not executed, because the execution was stopped,
+// are reported as not run. Their children are not known, because they are
+// declared only when the spec is executed.
+func (r *Runner) reportNotRunTasks() {
+	for _, task := range r.scheduled {
+		for _, spec := range task.notRunSpecs() {
+			spec.status = NotRun
+			r.addExecuted(spec)
+		}
+	}
+	r.scheduled = r.scheduled[:0]
+}
+
 func (r *Runner) Results() *ResultCollector {
 	// TODO: Should this be done concurrently with executing the specs?
 	// The result collector could run in its own goroutine, and the
@@ -115,10 +534,14 @@ func (r *Runner) Results() *ResultCollector {
 	// get ready (the channel should be buffered). When all is done, the runner
 	// will get the result collector from a result channel.
 
-	results := newResultCollector()
+	results := NewResultCollector()
 	for _, spec := range r.executed {
 		results.Update(spec)
 	}
+	if r.isShuffled() {
+		results.shuffled = true
+		results.seed = r.seed
+	}
 	return results
 }
 
@@ -126,19 +549,90 @@ func (r *Runner) Results() *ResultCollector {
 type scheduledTask struct {
 	name    string
 	closure specRoot
+	order   int
 	context *taskContext
+	spec    *specRun // the postponed spec which the task executes, if known
 }
 
 type specRoot func(Context)
 
-func newScheduledTask(name string, closure specRoot, context *taskContext) *scheduledTask {
-	return &scheduledTask{name, closure, context}
+func newScheduledTask(name string, closure specRoot, order int, context *taskContext) *scheduledTask {
+	return &scheduledTask{name, closure, order, context, nil}
+}
+
+// Returns the target spec of the task, beginning with its parents, if they
+// have not been reported. When the spec is not known, because the task is
+// not for a postponed or retried spec, it's created from the names of the
+// specs on the target path. A continuation after a timeout has no names,
+// but its target spec was already reported by the task which timed out.
+func (task *scheduledTask) notRunSpecs() []*specRun {
+	c := task.context
+	switch {
+	case task.spec != nil:
+		return []*specRun{task.spec}
+	case len(c.targetNames) == len(c.targetPath)+1:
+		return newSpecRunsOnPath(c.targetNames, c.targetPath)
+	case c.targetPath.isRoot():
+		return []*specRun{newSpecRun(task.name, nil, nil, rootPath())}
+	}
+	return nil
+}
+
+func newSpecRunsOnPath(names []string, targetPath path) []*specRun {
+	specs := make([]*specRun, 0)
+	var parent *specRun
+	for level, name := range names {
+		spec := newSpecRun(name, nil, parent, targetPath)
+		spec.path = targetPath[:level]
+		specs = append(specs, spec)
+		parent = spec
+	}
+	return specs
+}
+
+// The scheduled tasks are kept in a heap, so that the first declared task
+// can be found quickly when the specs are executed in declaration order.
+type scheduledTasks []*scheduledTask
+
+func (h scheduledTasks) Len() int            { return len(h) }
+func (h scheduledTasks) Less(i, j int) bool  { return h[i].isDeclaredBefore(h[j]) }
+func (h scheduledTasks) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *scheduledTasks) Push(x interface{}) { *h = append(*h, x.(*scheduledTask)) }
+func (h *scheduledTasks) Pop() interface{} {
+	old := *h
+	last := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return last
+}
+
+// Root specs are in the order in which they were added to the Runner,
+// and the specs of one root are in the order in which they are declared,
+// except that the tasks which declare the children of the root specs are
+// before all other tasks.
+func (task *scheduledTask) isDeclaredBefore(other *scheduledTask) bool {
+	if task.context.declareOnly != other.context.declareOnly {
+		return task.context.declareOnly
+	}
+	if task.order != other.order {
+		return task.order < other.order
+	}
+	return task.context.targetPath.isBefore(other.context.targetPath)
+}
+
+func retryKey(rootName string, path path) string {
+	return fmt.Sprint(rootName, []int(path))
 }
 
 // Results of a spec execution.
 type taskResult struct {
 	name           string
 	closure        specRoot
+	order          int
 	executedSpecs  []*specRun
 	postponedSpecs []*specRun
+	continuation   *taskContext
+	fallback       *taskContext
+	targetNames    []string
+	declareOnly    bool
+	stillRunning   <-chan bool // closed when the goroutine of a timed out task stops
 }
@@ -0,0 +1,164 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"container/heap"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sharding splits the root specs between multiple processes, for example
+// multiple CI machines, so that each of them executes only some of the
+// specs. The shards are numbered from 1 to the number of shards.
+type shard struct {
+	index     int
+	total     int
+	durations map[string]time.Duration // of the root specs on a previous run
+}
+
+// Executes only the root specs which belong to the shard. The root specs
+// are assigned to shards by the hash of their name, so that every root spec
+// is executed by exactly one of the shards, as long as all of them have
+// the same specs.
+func (r *Runner) Shard(index int, total int) error {
+	if total < 1 || index < 1 || index > total {
+		return fmt.Errorf("shard %v/%v is out of range", index, total)
+	}
+	r.shard = &shard{index, total, nil}
+	return nil
+}
+
+// Assigns the root specs to shards so that the shards take about as long
+// to execute, based on how long the root specs took on a previous run.
+// The root specs which were not executed on the previous run are expected
+// to take the average time. Must be called after Shard.
+func (r *Runner) BalanceShards(durations map[string]time.Duration) {
+	if r.shard != nil {
+		r.shard.durations = durations
+	}
+}
+
+func (r *Runner) selectShard() {
+	if r.shard == nil {
+		return
+	}
+	shards := r.shard.assign(r.rootNames)
+	scheduled := make(scheduledTasks, 0)
+	for _, task := range r.scheduled {
+		if shards[task.name] == r.shard.index {
+			scheduled = append(scheduled, task)
+		}
+	}
+	rootNames := make([]string, 0)
+	for _, name := range r.rootNames {
+		if shards[name] == r.shard.index {
+			rootNames = append(rootNames, name)
+		}
+	}
+	heap.Init(&scheduled)
+	r.scheduled = scheduled
+	r.rootNames = rootNames
+}
+
+// Returns how long it took to execute each of the root specs. Only the
+// durations of the root specs are needed for balancing the shards, because
+// the shards are made of whole root specs.
+func (r *ResultCollector) rootDurations() map[string]time.Duration {
+	durations := make(map[string]time.Duration)
+	for name, root := range r.rootsByName {
+		durations[name] = root.duration
+	}
+	return durations
+}
+
+// Returns the shard of each of the root specs.
+func (s *shard) assign(names []string) map[string]int {
+	if s.durations == nil {
+		shards := make(map[string]int)
+		for _, name := range names {
+			shards[name] = hashShard(name, s.total)
+		}
+		return shards
+	}
+	return balanceShards(names, s.durations, s.total)
+}
+
+func hashShard(name string, total int) int {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return int(h.Sum32()%uint32(total)) + 1
+}
+
+// Assigns the longest root specs first, each to the shard which has the
+// least work so far. The result is the same on every shard, because the
+// ties are broken by the names of the specs and the numbers of the shards.
+func balanceShards(names []string, durations map[string]time.Duration, total int) map[string]int {
+	expected := expectedDurations(names, durations)
+	sorted := append([]string{}, names...)
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if expected[a] != expected[b] {
+			return expected[a] > expected[b]
+		}
+		return a < b
+	})
+	loads := make([]time.Duration, total)
+	shards := make(map[string]int)
+	for _, name := range sorted {
+		least := 0
+		for i := range loads {
+			if loads[i] < loads[least] {
+				least = i
+			}
+		}
+		loads[least] += expected[name]
+		shards[name] = least + 1
+	}
+	return shards
+}
+
+func expectedDurations(names []string, durations map[string]time.Duration) map[string]time.Duration {
+	var sum time.Duration
+	known := 0
+	for _, name := range names {
+		if duration, found := durations[name]; found {
+			sum += duration
+			known++
+		}
+	}
+	average := time.Duration(1)
+	if known > 0 && sum > 0 {
+		average = sum / time.Duration(known)
+	}
+	expected := make(map[string]time.Duration)
+	for _, name := range names {
+		if duration, found := durations[name]; found {
+			expected[name] = duration
+		} else {
+			expected[name] = average
+		}
+	}
+	return expected
+}
+
+// Parses a shard in the form "index/total", for example "1/3".
+func parseShard(s string) (index int, total int, err error) {
+	parts := strings.Split(s, "/")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("expected index/total, but was %q", s)
+	}
+	if index, err = strconv.Atoi(parts[0]); err != nil {
+		return 0, 0, err
+	}
+	if total, err = strconv.Atoi(parts[1]); err != nil {
+		return 0, 0, err
+	}
+	return index, total, nil
+}
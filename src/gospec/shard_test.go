@@ -0,0 +1,109 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"fmt"
+	"github.com/orfjackal/nanospec.go/src/nanospec"
+	"time"
+)
+
+func ShardSpec(c nanospec.Context) {
+	names := []string{}
+	for i := 0; i < 20; i++ {
+		names = append(names, fmt.Sprintf("pkg.Spec%v", i))
+	}
+	runShard := func(index int, total int, durations map[string]time.Duration) *ResultCollector {
+		r := NewRunner()
+		for _, name := range names {
+			r.AddNamedSpec(name, DummySpecWithNoChildren)
+		}
+		c.Expect(r.Shard(index, total)).Equals(nil)
+		if durations != nil {
+			r.BalanceShards(durations)
+		}
+		r.Run()
+		return r.Results()
+	}
+
+	c.Specify("Every root spec is executed by exactly one of the shards", func() {
+		executed := make(map[string]int)
+		for i := 1; i <= 3; i++ {
+			results := runShard(i, 3, nil)
+			c.Expect(results.TotalCount() > 0).IsTrue()
+			for name := range results.rootsByName {
+				executed[name]++
+			}
+		}
+		c.Expect(len(executed)).Equals(len(names))
+		for _, name := range names {
+			c.Expect(executed[name]).Equals(1)
+		}
+	})
+
+	c.Specify("The root specs are always assigned to the same shards", func() {
+		c.Expect(hashShard("pkg.Spec1", 3)).Equals(hashShard("pkg.Spec1", 3))
+		c.Expect(runShard(2, 3, nil).sortedRootNames()).Equals(runShard(2, 3, nil).sortedRootNames())
+	})
+
+	c.Specify("With only one shard, all root specs are executed", func() {
+		c.Expect(runShard(1, 1, nil).TotalCount()).Equals(len(names))
+	})
+
+	c.Specify("Shards out of range are not allowed", func() {
+		r := NewRunner()
+		c.Expect(r.Shard(0, 3)).NotEquals(nil)
+		c.Expect(r.Shard(4, 3)).NotEquals(nil)
+		c.Expect(r.Shard(1, 0)).NotEquals(nil)
+	})
+
+	c.Specify("The shard is given as index/total", func() {
+		index, total, err := parseShard("2/3")
+		c.Expect(err).Equals(nil)
+		c.Expect(index).Equals(2)
+		c.Expect(total).Equals(3)
+		_, _, err = parseShard("2")
+		c.Expect(err).NotEquals(nil)
+		_, _, err = parseShard("a/3")
+		c.Expect(err).NotEquals(nil)
+	})
+
+	c.Specify("When balancing the shards by the durations of a previous run", func() {
+		durations := map[string]time.Duration{
+			"A": 10 * time.Second,
+			"B": 6 * time.Second,
+			"C": 5 * time.Second,
+			"D": 3 * time.Second,
+			"E": 1 * time.Second,
+		}
+
+		c.Specify("the longest root specs are assigned first to the least busy shard", func() {
+			shards := balanceShards([]string{"E", "D", "C", "B", "A"}, durations, 2)
+			c.Expect(shards).Equals(map[string]int{"A": 1, "B": 2, "C": 2, "D": 1, "E": 2})
+		})
+		c.Specify("the root specs without a previous duration are expected to take the average time", func() {
+			shards := balanceShards([]string{"A", "B", "New"}, durations, 2)
+			c.Expect(shards).Equals(map[string]int{"A": 1, "B": 2, "New": 2})
+		})
+		c.Specify("every root spec is executed by exactly one of the shards", func() {
+			total := 0
+			for i := 1; i <= 3; i++ {
+				total += runShard(i, 3, map[string]time.Duration{"pkg.Spec1": time.Second}).TotalCount()
+			}
+			c.Expect(total).Equals(len(names))
+		})
+	})
+
+	c.Specify("The durations of the root specs are recorded", func() {
+		r := NewRunner()
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			time.Sleep(10 * time.Millisecond)
+		})
+		r.Run()
+		duration := r.Results().rootDurations()["RootSpec"]
+		c.Expect(duration >= 10*time.Millisecond).IsTrue()
+		c.Expect(saveAndLoad(r.Results()).rootDurations()["RootSpec"]).Equals(duration)
+	})
+}
@@ -7,6 +7,7 @@ package gospec
 import (
 	"container/list"
 	"fmt"
+	"time"
 )
 
 // Represents a spec in a tree of specs.
@@ -15,36 +16,88 @@ type specRun struct {
 	closure          func()
 	parent           *specRun
 	numberOfChildren int
+	executedChildren int
 	path             path
 	targetPath       path
 	errors           *list.List
 	hasFatalErrors   bool
+	timeout          time.Duration
+	focused          bool
+	searched         bool // executed only for finding its children which match the focus pattern
+	status           Status
+	deferred         []func()
+	retries          int
+	started          time.Time
+	duration         time.Duration // including the children executed on the same run
 }
 
 func newSpecRun(name string, closure func(), parent *specRun, targetPath path) *specRun {
 	path := rootPath()
+	timeout := time.Duration(0)
+	retries := 0
 	if parent != nil {
 		currentIndex := parent.numberOfChildren
 		path = parent.path.append(currentIndex)
 		parent.numberOfChildren++
+		timeout = parent.timeout
+		retries = parent.retries
 	}
-	return &specRun{name, closure, parent, 0, path, targetPath, list.New(), false}
+	return &specRun{name, closure, parent, 0, 0, path, targetPath, list.New(), false, timeout, false, false, Passed, nil, retries, time.Time{}, 0}
 }
 
 func (spec *specRun) isOnTargetPath() bool { return spec.path.isOn(spec.targetPath) }
 func (spec *specRun) isUnseen() bool       { return spec.path.isBeyond(spec.targetPath) }
-func (spec *specRun) isFirstChild() bool   { return spec.path.lastIndex() == 0 }
+func (spec *specRun) isDisabled() bool     { return spec.status == Skipped || spec.status == Pending }
 
-func (spec *specRun) execute() {
+// On a spec's first run, only one of its children is executed at a time.
+// The rest of the children are postponed and executed on later runs.
+func (spec *specRun) hasExecutedSiblings() bool {
+	return spec.parent != nil && spec.parent.executedChildren > 0
+}
+
+func (spec *specRun) markExecuted() {
+	if spec.parent != nil {
+		spec.parent.executedChildren++
+	}
+}
+
+// Panicked by Context.Skip to stop executing the closure of the spec.
+type skipRequest struct{}
+
+func (spec *specRun) execute(log ratedErrorLogger) {
 	exception := recoverOnPanic(spec.closure)
-	if exception != nil {
+	if exception != nil && !isSkipRequest(exception.Cause) {
 		spec.fixupStackTraceForRootSpec(exception)
-		spec.AddFatalError(exception.ToError())
+		log.AddFatalError(exception.ToError())
+	}
+}
+
+// Removes the deferred functions, so that they are executed only once.
+func (spec *specRun) takeDeferred() []func() {
+	deferred := spec.deferred
+	spec.deferred = nil
+	return deferred
+}
+
+// Deferred functions are executed in reverse order, the same way as Go's
+// defer statements, and a panic in one of them does not prevent the others
+// from being executed.
+func runDeferred(deferred []func(), log ratedErrorLogger) {
+	for i := len(deferred) - 1; i >= 0; i-- {
+		exception := recoverOnPanic(deferred[i])
+		if exception != nil && !isSkipRequest(exception.Cause) {
+			log.AddError(exception.ToError())
+		}
 	}
 }
 
+func isSkipRequest(cause interface{}) bool {
+	_, ok := cause.(skipRequest)
+	return ok
+}
+
 func (spec *specRun) fixupStackTraceForRootSpec(e *exception) {
-	if spec.path.isRoot() {
+	if spec.path.isRoot() && len(e.StackTrace) > 0 {
 		// Remove the stack frame which comes when gospec.Runner.execute()
 		// wraps the root spec (which takes Context as a parameter)
 		// into a closure (which takes no parameters).
@@ -53,6 +106,16 @@ func (spec *specRun) fixupStackTraceForRootSpec(e *exception) {
 	}
 }
 
+// The reason given to Context.Skip is not a failure.
+func (spec *specRun) hasFailures() bool {
+	for e := spec.errors.Front(); e != nil; e = e.Next() {
+		if e.Value.(*Error).Type != SkipRequested {
+			return true
+		}
+	}
+	return false
+}
+
 func (spec *specRun) AddError(error *Error) {
 	spec.errors.PushBack(error)
 }
@@ -62,6 +125,11 @@ func (spec *specRun) AddFatalError(error *Error) {
 	spec.hasFatalErrors = true
 }
 
+func (spec *specRun) clearErrors() {
+	spec.errors = list.New()
+	spec.hasFatalErrors = false
+}
+
 func (spec *specRun) rootParent() *specRun {
 	root := spec
 	for root.parent != nil {
@@ -113,6 +181,14 @@ func (current path) isBeyond(target path) bool {
 	return target.isOn(current) && len(current) > len(target)
 }
 
+func (current path) isBefore(target path) bool {
+	common := commonPrefixLength(current, target)
+	if common == len(current) || common == len(target) {
+		return len(current) < len(target)
+	}
+	return current[common] < target[common]
+}
+
 func commonPrefixLength(a path, b path) int {
 	length := 0
 	for i := 0; i < len(a) && i < len(b) && a[i] == b[i]; i++ {
@@ -0,0 +1,43 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+// Status tells what happened to a spec when it was executed.
+type Status int
+
+const (
+	Passed Status = iota
+	Failed
+	Skipped // disabled with XSpecify or not related to the focused specs
+	Pending // declared with Pending
+	Aborted // not executed, because an assumption of a parent spec failed
+	NotRun  // not executed, because the execution was stopped after a failure
+	Flaky   // passed when it was retried after failing
+)
+
+func (this Status) String() string {
+	switch this {
+	case Passed:
+		return "passed"
+	case Failed:
+		return "failed"
+	case Skipped:
+		return "skipped"
+	case Pending:
+		return "pending"
+	case Aborted:
+		return "aborted"
+	case NotRun:
+		return "not run"
+	case Flaky:
+		return "flaky"
+	}
+	return "unknown"
+}
+
+// Tells whether the spec was executed, regardless of whether it passed.
+func (this Status) WasExecuted() bool {
+	return this == Passed || this == Failed || this == Flaky
+}
@@ -0,0 +1,118 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode"
+)
+
+// Executes the specs which have been added to the Runner and reports
+// every spec as a subtest of the surrounding test, so that gotest's
+// -run, -skip, -json and -failfast parameters and IDEs know about the
+// individual specs. The names of the subtests are the names of the specs,
+// with spaces and slashes replaced by underscores, and the package path
+// removed from the names of the root specs. For example:
+//
+//	go test -run 'TestAllSpecs/StackSpec/An_empty_stack'
+//
+// The specs are executed before reporting them as subtests, so the
+// durations of the subtests are not the durations of the specs.
+func MainGoSubtests(runner *Runner, t *testing.T) {
+	t.Helper()
+	if err := configure(runner); err != nil {
+		t.Fatal(err)
+	}
+	if err := configureSubtests(runner, t.Name(), testFlag("test.run"), testFlag("test.skip"), testFlag("test.failfast") == "true"); err != nil {
+		t.Fatal(err)
+	}
+	results := run(runner)
+	if err := saveResults(results, t.Name()); err != nil {
+		t.Fatal(err)
+	}
+	for root := range results.sortedRoots() {
+		reportAsSubtest(t, root)
+	}
+	if *failOnFocus && results.HasFocusedSpecs() {
+		t.Error("Some specs are focused with FSpecify")
+	}
+}
+
+// Executes only the specs which are selected by gotest's -run and -skip
+// parameters, in addition to the specs selected by GoSpec's own parameters.
+// Their first levels select the surrounding test, and the rest are matched
+// against the subtest names of the specs. With -failfast the specs after
+// the first failure are not executed.
+func configureSubtests(runner *Runner, testName string, run string, skip string, failFast bool) error {
+	levels := len(strings.Split(testName, "/"))
+	if err := runner.filter.setSubtestFocus(dropNamePatternLevels(run, levels)); err != nil {
+		return fmt.Errorf("invalid -test.run: %v", err)
+	}
+	if err := runner.filter.setSubtestSkip(dropNamePatternLevels(skip, levels)); err != nil {
+		return fmt.Errorf("invalid -test.skip: %v", err)
+	}
+	if failFast {
+		runner.FailFast()
+	}
+	return nil
+}
+
+func testFlag(name string) string {
+	if f := flag.Lookup(name); f != nil {
+		return f.Value.String()
+	}
+	return ""
+}
+
+func subtestName(name string, path path) string {
+	if path.isRoot() {
+		name = name[strings.LastIndex(name, "/")+1:]
+		if dot := strings.Index(name, "."); dot >= 0 {
+			name = name[dot+1:]
+		}
+	}
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || r == '/' {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+func reportAsSubtest(t *testing.T, spec *specResult) {
+	t.Helper()
+	t.Run(subtestName(spec.name, spec.path), func(t *testing.T) {
+		t.Helper()
+		for e := spec.errors.Front(); e != nil; e = e.Next() {
+			if error := e.Value.(*Error); error.Type != SkipRequested {
+				fmt.Fprint(t.Output(), subtestErrorMessage(error))
+				t.Fail()
+			}
+		}
+		for e := spec.children.Front(); e != nil; e = e.Next() {
+			reportAsSubtest(t, e.Value.(*specResult))
+		}
+		switch status := spec.status(); status {
+		case Skipped, Pending, Aborted, NotRun:
+			t.Skip(statusLabel(status, skipReason(listToErrorArray(spec.errors))))
+		case Flaky:
+			t.Log(statusLabel(status, ""))
+		}
+	})
+}
+
+// The errors are reported at the location where they happened, the same
+// way as t.Error reports the location where it was called.
+func subtestErrorMessage(e *Error) string {
+	message := formatErrorWithStackTrace(e)
+	if len(e.StackTrace) > 0 {
+		loc := e.StackTrace[0]
+		message = fmt.Sprintf("%v:%v: %v", loc.FileName(), loc.Line(), message)
+	}
+	return message + "\n"
+}
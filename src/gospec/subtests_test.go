@@ -0,0 +1,95 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"fmt"
+	"github.com/orfjackal/nanospec.go/src/nanospec"
+	"strings"
+)
+
+func SubtestsSpec(c nanospec.Context) {
+
+	c.Specify("Subtest names", func() {
+
+		c.Specify("have spaces and slashes replaced with underscores", func() {
+			c.Expect(subtestName("An empty stack/queue", path{0})).Equals("An_empty_stack_queue")
+		})
+		c.Specify("of root specs do not contain the package path", func() {
+			c.Expect(subtestName("github.com/orfjackal/gospec/src/examples.StackSpec", rootPath())).Equals("StackSpec")
+			c.Expect(subtestName("RootSpec", rootPath())).Equals("RootSpec")
+		})
+	})
+
+	c.Specify("The levels of gotest's -run pattern which select the test are dropped", func() {
+		c.Expect(dropNamePatternLevels("TestAllSpecs/StackSpec/empty", 1)).Equals("StackSpec/empty")
+		c.Expect(dropNamePatternLevels("TestAllSpecs", 1)).Equals("")
+		c.Expect(dropNamePatternLevels("", 1)).Equals("")
+	})
+
+	c.Specify("When selecting subtests", func() {
+		resetTestSpy()
+		r := NewRunner()
+		r.AddNamedSpec("example.RootSpec", DummySpecWithMultipleNestedChildren)
+
+		c.Specify("the -run pattern is matched against the subtest names level by level", func() {
+			err := configureSubtests(r, "TestAllSpecs", "TestAllSpecs/RootSpec/A$/AB", "", false)
+			r.RunSerially()
+			c.Expect(err).Equals(nil)
+			c.Expect(testSpy).Equals("root" + "root,a,ab")
+		})
+		c.Specify("the -skip pattern is matched against the subtest names", func() {
+			err := configureSubtests(r, "TestAllSpecs", "", "TestAllSpecs/RootSpec/Child_B", false)
+			r.RunSerially()
+			c.Expect(err).Equals(nil)
+			c.Expect(testSpy).Equals("root" + "root,a,aaroot,a,ab")
+		})
+		c.Specify("the -run pattern and the -gospec.focus pattern must both match", func() {
+			r.Focus("Child AB|Child BA")
+			err := configureSubtests(r, "TestAllSpecs", "TestAllSpecs/RootSpec/Child_A", "", false)
+			r.Run()
+			c.Expect(err).Equals(nil)
+			c.Expect(r.Results()).Matches(ReportIs(`
+- example.RootSpec
+  - Child A
+    - Child AB
+
+3 specs, 0 failures
+`))
+		})
+		c.Specify("invalid patterns are reported as errors", func() {
+			err := configureSubtests(r, "TestAllSpecs", "TestAllSpecs/(", "", false)
+			c.Expect(err).Satisfies(err != nil)
+		})
+	})
+
+	c.Specify("When gotest's -failfast parameter is given", func() {
+		r := NewRunner()
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			c.Specify("Failing", func() {
+				c.Expect(1, Equals, 2)
+			})
+			c.Specify("Not executed", func() {})
+		})
+		configureSubtests(r, "TestAllSpecs", "", "", true)
+		r.RunSerially()
+
+		c.Specify("the specs after the first failure are not executed", func() {
+			c.Expect(r.Results().NotRunCount()).Equals(1)
+		})
+	})
+
+	c.Specify("The errors of the subtests are reported at the location where they happened", func() {
+		results := runSpec(func(c Context) {
+			c.Expect(1, Equals, 2)
+		})
+		var failure *Error
+		for root := range results.sortedRoots() {
+			failure = root.errors.Front().Value.(*Error)
+		}
+		message := subtestErrorMessage(failure)
+		c.Expect(message).Satisfies(strings.HasPrefix(message, fmt.Sprintf("subtests_test.go:%v: ", failure.StackTrace[0].Line())))
+	})
+}
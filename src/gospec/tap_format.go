@@ -0,0 +1,160 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// PrintFormat for the Test Anything Protocol, version 13, for tools which
+// combine the test results of many languages. The nested specs are indented
+// subtests, and the errors of failed specs are YAML diagnostics. The result
+// line of a spec can be printed only after its children, so the output is
+// complete only after the summary has been printed.
+func TapPrintFormat(out io.Writer) PrintFormat {
+	return &tapPrintFormat{out: out}
+}
+
+type tapPrintFormat struct {
+	out     io.Writer
+	started bool
+	roots   int
+	open    []*tapTest // the specs whose children may still be printed, by nesting level
+}
+
+type tapTest struct {
+	level       int
+	number      int
+	name        string
+	ok          bool
+	description string
+	errors      []*Error
+	children    int
+}
+
+func (this *tapPrintFormat) PrintPassing(nestingLevel int, name string) {
+	this.begin(&tapTest{level: nestingLevel, name: name, ok: true})
+}
+
+func (this *tapPrintFormat) PrintFailing(nestingLevel int, name string, errors []*Error) {
+	failures := []*Error{}
+	for _, error := range errors {
+		if error.Type != SkipRequested {
+			failures = append(failures, error)
+		}
+	}
+	this.begin(&tapTest{level: nestingLevel, name: name, ok: false, errors: failures})
+}
+
+func (this *tapPrintFormat) PrintSkipped(nestingLevel int, name string, status Status, reason string) {
+	test := &tapTest{level: nestingLevel, name: name, ok: true}
+	if status == Flaky {
+		test.description = " " + statusLabel(status, "")
+	} else {
+		if reason == "" {
+			reason = status.String()
+		}
+		test.description = " # SKIP " + reason
+	}
+	this.begin(test)
+}
+
+func (this *tapPrintFormat) PrintSummary(summary Summary) {
+	this.start()
+	this.closeTests(0)
+	fmt.Fprintf(this.out, "1..%v\n", this.roots)
+	fmt.Fprintf(this.out, "# %v\n", formatSpecCounts(summary))
+	if summary.Shuffled {
+		fmt.Fprintf(this.out, "# Randomized with seed %v\n", summary.Seed)
+	}
+}
+
+func (this *tapPrintFormat) start() {
+	if !this.started {
+		fmt.Fprintf(this.out, "TAP version 13\n")
+		this.started = true
+	}
+}
+
+// The siblings of the spec, and their children, are complete when the next
+// spec on the same or a higher level begins.
+func (this *tapPrintFormat) begin(test *tapTest) {
+	this.start()
+	this.closeTests(test.level)
+	if test.level > 0 && len(this.open) > 0 {
+		parent := this.open[len(this.open)-1]
+		parent.children++
+		if parent.children == 1 {
+			fmt.Fprintf(this.out, "%v# Subtest: %v\n", tapIndent(test.level), parent.name)
+		}
+		test.number = parent.children
+	} else {
+		this.roots++
+		test.number = this.roots
+	}
+	this.open = append(this.open, test)
+}
+
+func (this *tapPrintFormat) closeTests(level int) {
+	for len(this.open) > level {
+		last := len(this.open) - 1
+		test := this.open[last]
+		this.open = this.open[:last]
+		if !test.ok && last > 0 {
+			this.open[last-1].ok = false
+		}
+		this.printResult(test)
+	}
+}
+
+func (this *tapPrintFormat) printResult(test *tapTest) {
+	indent := tapIndent(test.level)
+	if test.children > 0 {
+		fmt.Fprintf(this.out, "%v1..%v\n", tapIndent(test.level+1), test.children)
+	}
+	result := "ok"
+	if !test.ok {
+		result = "not ok"
+	}
+	name := strings.Replace(test.name, "#", `\#`, -1)
+	fmt.Fprintf(this.out, "%v%v %v - %v%v\n", indent, result, test.number, name, test.description)
+	this.printDiagnostics(indent+"  ", test.errors)
+}
+
+// The diagnostics are a YAML document, with the strings in double quotes,
+// which accept the same escape sequences as Go.
+func (this *tapPrintFormat) printDiagnostics(indent string, errors []*Error) {
+	if len(errors) == 0 {
+		return
+	}
+	fmt.Fprintf(this.out, "%v---\n", indent)
+	fmt.Fprintf(this.out, "%verrors:\n", indent)
+	for _, error := range errors {
+		fmt.Fprintf(this.out, "%v  - type: %v\n", indent, error.Type)
+		switch error.Type {
+		case ExpectFailed, AssumeFailed:
+			fmt.Fprintf(this.out, "%v    expected: %v\n", indent, strconv.Quote(error.Message))
+			fmt.Fprintf(this.out, "%v    actual: %v\n", indent, strconv.Quote(error.Actual))
+		default:
+			fmt.Fprintf(this.out, "%v    message: %v\n", indent, strconv.Quote(error.Message))
+		}
+		if len(error.StackTrace) > 0 {
+			fmt.Fprintf(this.out, "%v    at:\n", indent)
+		}
+		for _, loc := range error.StackTrace {
+			fmt.Fprintf(this.out, "%v      - function: %v\n", indent, strconv.Quote(loc.Name()))
+			fmt.Fprintf(this.out, "%v        file: %v\n", indent, strconv.Quote(loc.File()))
+			fmt.Fprintf(this.out, "%v        line: %v\n", indent, loc.Line())
+		}
+	}
+	fmt.Fprintf(this.out, "%v...\n", indent)
+}
+
+func tapIndent(level int) string {
+	return strings.Repeat("    ", level)
+}
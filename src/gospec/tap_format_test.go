@@ -0,0 +1,123 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"bytes"
+	"github.com/orfjackal/nanospec.go/src/nanospec"
+	"strings"
+)
+
+func TapFormatSpec(c nanospec.Context) {
+	trim := strings.TrimSpace
+	out := new(bytes.Buffer)
+	p := NewPrinter(TapPrintFormat(out))
+	p.ShowAll()
+
+	c.Specify("The specs are numbered tests, followed by the plan and the summary", func() {
+		p.VisitSpec(0, "Passing 1", Passed, noErrors)
+		p.VisitSpec(0, "Passing 2", Passed, noErrors)
+		p.VisitEnd(Summary{PassCount: 2})
+		c.Expect(trim(out.String())).Equals(trim(`
+TAP version 13
+ok 1 - Passing 1
+ok 2 - Passing 2
+1..2
+# 2 specs, 0 failures
+`))
+	})
+
+	c.Specify("Nested specs are subtests, which are printed before their parent", func() {
+		p.VisitSpec(0, "Parent", Passed, noErrors)
+		p.VisitSpec(1, "Child 1", Passed, noErrors)
+		p.VisitSpec(2, "Grandchild", Passed, noErrors)
+		p.VisitSpec(1, "Child 2", Passed, noErrors)
+		p.VisitSpec(0, "Other", Passed, noErrors)
+		p.VisitEnd(Summary{PassCount: 5})
+		c.Expect(trim(out.String())).Equals(trim(`
+TAP version 13
+    # Subtest: Parent
+        # Subtest: Child 1
+        ok 1 - Grandchild
+        1..1
+    ok 1 - Child 1
+    ok 2 - Child 2
+    1..2
+ok 1 - Parent
+ok 2 - Other
+1..2
+# 5 specs, 0 failures
+`))
+	})
+
+	c.Specify("Failing specs and their parents are not ok, and the errors are YAML diagnostics", func() {
+		failure := newError(ExpectFailed, "equals “2”", "1", []*Location{{"pkg.FooSpec", "/src/foo_test.go", 12}})
+		p.VisitSpec(0, "Parent", Passed, noErrors)
+		p.VisitSpec(1, "Failing", Failed, []*Error{failure})
+		p.VisitSpec(1, "Erroring", Failed, someError)
+		p.VisitEnd(Summary{PassCount: 1, FailCount: 2})
+		c.Expect(trim(out.String())).Equals(trim(`
+TAP version 13
+    # Subtest: Parent
+    not ok 1 - Failing
+      ---
+      errors:
+        - type: ExpectFailed
+          expected: "equals “2”"
+          actual: "1"
+          at:
+            - function: "pkg.FooSpec"
+              file: "/src/foo_test.go"
+              line: 12
+      ...
+    not ok 2 - Erroring
+      ---
+      errors:
+        - type: OtherError
+          message: "some error"
+      ...
+    1..2
+not ok 1 - Parent
+1..1
+# 3 specs, 2 failures
+`))
+	})
+
+	c.Specify("Skipped, pending and not run specs have a SKIP directive with their reason", func() {
+		p.VisitSpec(0, "Skipped", Skipped, []*Error{newError(SkipRequested, "some reason", "", []*Location{})})
+		p.VisitSpec(0, "Pending", Pending, noErrors)
+		p.VisitSpec(0, "Not run", NotRun, noErrors)
+		p.VisitEnd(Summary{SkipCount: 1, PendingCount: 1, NotRunCount: 1})
+		c.Expect(trim(out.String())).Equals(trim(`
+TAP version 13
+ok 1 - Skipped # SKIP some reason
+ok 2 - Pending # SKIP pending
+ok 3 - Not run # SKIP not run
+1..3
+# 3 specs, 0 failures, 1 skipped, 1 pending, 1 not run
+`))
+	})
+
+	c.Specify("Flaky specs are ok, but labeled as flaky", func() {
+		p.VisitSpec(0, "Flaky", Flaky, noErrors)
+		p.VisitEnd(Summary{FlakyCount: 1})
+		c.Expect(strings.Contains(out.String(), "\nok 1 - Flaky [FLAKY]\n")).IsTrue()
+	})
+
+	c.Specify("Hash signs in the names are escaped, so that they are not directives", func() {
+		p.VisitSpec(0, "Issue #1", Passed, noErrors)
+		p.VisitEnd(Summary{PassCount: 1})
+		c.Expect(strings.Contains(out.String(), "\nok 1 - Issue \\#1\n")).IsTrue()
+	})
+
+	c.Specify("Without any specs, the plan is empty", func() {
+		p.VisitEnd(Summary{})
+		c.Expect(trim(out.String())).Equals(trim(`
+TAP version 13
+1..0
+# 0 specs, 0 failures
+`))
+	})
+}
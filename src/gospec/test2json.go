@@ -0,0 +1,177 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// The name of the test function which executes the specs, when it is not
+// known. The all_specs_test.go files generated by gospecgen use this name.
+const DefaultTestName = "TestAllSpecs"
+
+// The events of `go test -json`, as described by `go doc test2json`, so
+// that Go's test tools and IDEs can show the specs as individual tests
+// without executing them with testing.T.Run. Every spec is a subtest of
+// the test function, named the same way as by MainGoSubtests. The results
+// of every package, which is known by the names of the root specs, are
+// reported as if they were the results of one go test run.
+type testEvent struct {
+	Time    time.Time
+	Action  string
+	Package string
+	Test    string   `json:",omitempty"`
+	Elapsed *float64 `json:",omitempty"` // in seconds
+	Output  string   `json:",omitempty"`
+}
+
+type testEventWriter struct {
+	encoder *json.Encoder
+	pkg     string
+	err     error
+}
+
+// Writes the results as `go test -json` events. The specs are reported as
+// the subtests of the test function testName.
+func (r *ResultCollector) WriteTestEvents(out io.Writer, testName string) error {
+	packages := []string{}
+	rootsByPackage := make(map[string][]*specResult)
+	for root := range r.sortedRoots() {
+		pkg := packageOf(root.name)
+		if _, found := rootsByPackage[pkg]; !found {
+			packages = append(packages, pkg)
+		}
+		rootsByPackage[pkg] = append(rootsByPackage[pkg], root)
+	}
+	encoder := json.NewEncoder(out)
+	for _, pkg := range packages {
+		w := &testEventWriter{encoder: encoder, pkg: pkg}
+		w.writePackage(testName, rootsByPackage[pkg])
+		if w.err != nil {
+			return w.err
+		}
+	}
+	return nil
+}
+
+func (w *testEventWriter) writePackage(testName string, roots []*specResult) {
+	var duration time.Duration
+	action := "pass"
+	for _, root := range roots {
+		duration += root.duration
+		if testAction(root) == "fail" {
+			action = "fail"
+		}
+	}
+	w.write(&testEvent{Action: "start", Package: w.pkg})
+	w.start(testName)
+	names := make(map[string]int)
+	for _, root := range roots {
+		w.writeSpec(uniqueTestName(testName, root, names), root, 1)
+	}
+	w.end(testName, 0, action, duration)
+	if action == "pass" {
+		w.output("", "PASS\n")
+		w.output("", fmt.Sprintf("ok  \t%v\t%.3fs\n", w.pkg, duration.Seconds()))
+	} else {
+		w.output("", "FAIL\n")
+		w.output("", fmt.Sprintf("FAIL\t%v\t%.3fs\n", w.pkg, duration.Seconds()))
+	}
+	w.write(&testEvent{Action: action, Package: w.pkg, Elapsed: seconds(duration)})
+}
+
+func (w *testEventWriter) writeSpec(name string, spec *specResult, depth int) {
+	indent := strings.Repeat("    ", depth+1)
+	w.start(name)
+	for e := spec.errors.Front(); e != nil; e = e.Next() {
+		if error := e.Value.(*Error); error.Type != SkipRequested {
+			for _, line := range strings.Split(formatErrorWithStackTrace(error), "\n") {
+				w.output(name, indent+line+"\n")
+			}
+		}
+	}
+	names := make(map[string]int)
+	for e := spec.children.Front(); e != nil; e = e.Next() {
+		child := e.Value.(*specResult)
+		w.writeSpec(uniqueTestName(name, child, names), child, depth+1)
+	}
+	switch status := spec.status(); status {
+	case Skipped, Pending, Aborted, NotRun:
+		w.output(name, indent+statusLabel(status, skipReason(listToErrorArray(spec.errors)))+"\n")
+	case Flaky:
+		w.output(name, indent+statusLabel(status, "")+"\n")
+	}
+	w.end(name, depth, testAction(spec), spec.duration)
+}
+
+func (w *testEventWriter) start(name string) {
+	w.write(&testEvent{Action: "run", Package: w.pkg, Test: name})
+	w.output(name, fmt.Sprintf("=== RUN   %v\n", name))
+}
+
+func (w *testEventWriter) end(name string, depth int, action string, duration time.Duration) {
+	indent := strings.Repeat("    ", depth)
+	w.output(name, fmt.Sprintf("%v--- %v: %v (%.2fs)\n", indent, strings.ToUpper(action), name, duration.Seconds()))
+	w.write(&testEvent{Action: action, Package: w.pkg, Test: name, Elapsed: seconds(duration)})
+}
+
+func (w *testEventWriter) output(name string, output string) {
+	w.write(&testEvent{Action: "output", Package: w.pkg, Test: name, Output: output})
+}
+
+func (w *testEventWriter) write(event *testEvent) {
+	event.Time = time.Now()
+	if w.err == nil {
+		w.err = w.encoder.Encode(event)
+	}
+}
+
+// Like testing.T.Run, a number is appended to the names of the siblings
+// which have the same name, so that every test has a unique name.
+func uniqueTestName(parentName string, spec *specResult, names map[string]int) string {
+	name := parentName + "/" + subtestName(spec.name, spec.path)
+	count := names[name]
+	names[name]++
+	if count > 0 {
+		name += fmt.Sprintf("#%02d", count)
+	}
+	return name
+}
+
+// Like with subtests, a spec fails also when some of its children fail.
+func testAction(spec *specResult) string {
+	action := "pass"
+	switch spec.status() {
+	case Failed:
+		return "fail"
+	case Skipped, Pending, Aborted, NotRun:
+		action = "skip"
+	}
+	for e := spec.children.Front(); e != nil; e = e.Next() {
+		if testAction(e.Value.(*specResult)) == "fail" {
+			return "fail"
+		}
+	}
+	return action
+}
+
+// The names of the root specs begin with the import path of their package,
+// for example "github.com/orfjackal/gospec/src/examples.StackSpec".
+func packageOf(rootName string) string {
+	slash := strings.LastIndex(rootName, "/")
+	if dot := strings.Index(rootName[slash+1:], "."); dot >= 0 {
+		return rootName[:slash+1+dot]
+	}
+	return ""
+}
+
+func seconds(duration time.Duration) *float64 {
+	s := duration.Seconds()
+	return &s
+}
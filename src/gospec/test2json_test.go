@@ -0,0 +1,170 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/orfjackal/nanospec.go/src/nanospec"
+	"strings"
+)
+
+func writeTestEvents(results *ResultCollector) []*testEvent {
+	out := new(bytes.Buffer)
+	if err := results.WriteTestEvents(out, "TestAllSpecs"); err != nil {
+		panic(err)
+	}
+	events := []*testEvent{}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		event := &testEvent{}
+		if err := json.Unmarshal([]byte(line), event); err != nil {
+			panic(err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// Lists the actions of the events, except output, with the test names.
+func testActions(events []*testEvent) []string {
+	actions := []string{}
+	for _, event := range events {
+		if event.Action != "output" {
+			actions = append(actions, strings.TrimSpace(event.Action+" "+event.Test))
+		}
+	}
+	return actions
+}
+
+func testOutput(events []*testEvent, test string) string {
+	output := ""
+	for _, event := range events {
+		if event.Action == "output" && event.Test == test {
+			output += event.Output
+		}
+	}
+	return output
+}
+
+func GoTestJSONSpec(c nanospec.Context) {
+
+	c.Specify("The specs are reported as nested subtests of the test function", func() {
+		events := writeTestEvents(runSpec(func(c Context) {
+			c.Specify("Parent spec", func() {
+				c.Specify("Child", func() {})
+			})
+		}))
+		c.Expect(testActions(events)).Equals([]string{
+			"start",
+			"run TestAllSpecs",
+			"run TestAllSpecs/RootSpec",
+			"run TestAllSpecs/RootSpec/Parent_spec",
+			"run TestAllSpecs/RootSpec/Parent_spec/Child",
+			"pass TestAllSpecs/RootSpec/Parent_spec/Child",
+			"pass TestAllSpecs/RootSpec/Parent_spec",
+			"pass TestAllSpecs/RootSpec",
+			"pass TestAllSpecs",
+			"pass",
+		})
+	})
+
+	c.Specify("The output is the same as with go test -v", func() {
+		events := writeTestEvents(runSpec(func(c Context) {
+			c.Specify("Child", func() {})
+		}))
+		c.Expect(testOutput(events, "TestAllSpecs/RootSpec/Child")).Equals(
+			"=== RUN   TestAllSpecs/RootSpec/Child\n" +
+				"        --- PASS: TestAllSpecs/RootSpec/Child (0.00s)\n")
+	})
+
+	c.Specify("The package is known by the names of the root specs", func() {
+		r := NewRunner()
+		r.AddNamedSpec("example.com/a.ASpec", DummySpecWithNoChildren)
+		r.AddNamedSpec("example.com/b.BSpec", DummySpecWithNoChildren)
+		r.Run()
+		events := writeTestEvents(r.Results())
+		c.Expect(testActions(events)).Equals([]string{
+			"start",
+			"run TestAllSpecs",
+			"run TestAllSpecs/ASpec",
+			"pass TestAllSpecs/ASpec",
+			"pass TestAllSpecs",
+			"pass",
+			"start",
+			"run TestAllSpecs",
+			"run TestAllSpecs/BSpec",
+			"pass TestAllSpecs/BSpec",
+			"pass TestAllSpecs",
+			"pass",
+		})
+		c.Expect(events[0].Package).Equals("example.com/a")
+		c.Expect(events[len(events)-1].Package).Equals("example.com/b")
+	})
+
+	c.Specify("A failing spec fails also its parents, and its errors are in the output", func() {
+		events := writeTestEvents(runSpec(func(c Context) {
+			c.Specify("Failing", func() {
+				c.Expect(1, Equals, 2)
+			})
+			c.Specify("Passing", func() {})
+		}))
+		c.Expect(testActions(events)).Equals([]string{
+			"start",
+			"run TestAllSpecs",
+			"run TestAllSpecs/RootSpec",
+			"run TestAllSpecs/RootSpec/Failing",
+			"fail TestAllSpecs/RootSpec/Failing",
+			"run TestAllSpecs/RootSpec/Passing",
+			"pass TestAllSpecs/RootSpec/Passing",
+			"fail TestAllSpecs/RootSpec",
+			"fail TestAllSpecs",
+			"fail",
+		})
+		output := testOutput(events, "TestAllSpecs/RootSpec/Failing")
+		c.Expect(strings.Contains(output, "\n            *** Expected: equals “2”\n")).IsTrue()
+		c.Expect(strings.Contains(output, "test2json_test.go:")).IsTrue()
+	})
+
+	c.Specify("Skipped and pending specs are skipped, with their reason in the output", func() {
+		events := writeTestEvents(runSpec(func(c Context) {
+			c.Specify("Skipped", func() {
+				c.Skip("some reason")
+			})
+			c.Pending("Pending")
+		}))
+		c.Expect(testActions(events)).Equals([]string{
+			"start",
+			"run TestAllSpecs",
+			"run TestAllSpecs/RootSpec",
+			"run TestAllSpecs/RootSpec/Skipped",
+			"skip TestAllSpecs/RootSpec/Skipped",
+			"run TestAllSpecs/RootSpec/Pending",
+			"skip TestAllSpecs/RootSpec/Pending",
+			"pass TestAllSpecs/RootSpec",
+			"pass TestAllSpecs",
+			"pass",
+		})
+		c.Expect(strings.Contains(testOutput(events, "TestAllSpecs/RootSpec/Skipped"), "[SKIP: some reason]")).IsTrue()
+	})
+
+	c.Specify("Only the pass, fail and skip events have the elapsed time", func() {
+		events := writeTestEvents(runSpec(DummySpecWithNoChildren))
+		for _, event := range events {
+			c.Expect(event.Elapsed != nil).Equals(event.Action == "pass" || event.Action == "fail" || event.Action == "skip")
+		}
+	})
+	c.Specify("Siblings with the same name are numbered, like subtests", func() {
+		events := writeTestEvents(runSpec(func(c Context) {
+			c.Specify("Same", func() {})
+			c.Specify("Same", func() {})
+		}))
+		c.Expect(testActions(events)[3:7]).Equals([]string{
+			"run TestAllSpecs/RootSpec/Same",
+			"pass TestAllSpecs/RootSpec/Same",
+			"run TestAllSpecs/RootSpec/Same#01",
+			"pass TestAllSpecs/RootSpec/Same#01",
+		})
+	})
+}
@@ -0,0 +1,212 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Watchdog notices when a spec of a task does not finish within its
+// timeout, which is measured from the time when the spec started, including
+// the time spent in its children. When a parent spec runs out of time, the
+// child which is executing fails. The goroutine of a timed out task cannot
+// be stopped, so it keeps running until it calls the Context the next time.
+type watchdog struct {
+	mutex     sync.Mutex
+	spec      *specRun // the innermost spec which is executing
+	expiring  *specRun // the spec whose deadline is the earliest
+	deadline  time.Time
+	changed   chan bool
+	goroutine int
+	timedOut  bool
+}
+
+func newWatchdog() *watchdog {
+	return &watchdog{changed: make(chan bool, 1)}
+}
+
+// Must be called by the goroutine which executes the specs.
+func (w *watchdog) watchCurrentGoroutine() {
+	w.goroutine = currentGoroutineId()
+}
+
+// Must be called while holding the mutex.
+func (w *watchdog) watch(spec *specRun) {
+	w.spec = spec
+	w.expiring = nil
+	w.deadline = time.Time{}
+	for current := spec; current != nil; current = current.parent {
+		if current.timeout <= 0 || current.started.IsZero() {
+			continue
+		}
+		deadline := current.started.Add(current.timeout)
+		if w.deadline.IsZero() || deadline.Before(w.deadline) {
+			w.expiring = current
+			w.deadline = deadline
+		}
+	}
+	select {
+	case w.changed <- true:
+	default:
+	}
+}
+
+// Returns when the task is done or it has timed out.
+func (w *watchdog) waitUntilFinished(done <-chan bool) {
+	for !w.waitUntilFinishedOrChanged(done) {
+	}
+}
+
+func (w *watchdog) waitUntilFinishedOrChanged(done <-chan bool) bool {
+	w.mutex.Lock()
+	deadline := w.deadline
+	w.mutex.Unlock()
+
+	var timeout <-chan time.Time
+	if !deadline.IsZero() {
+		timer := time.NewTimer(deadline.Sub(time.Now()))
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case <-done:
+		return true
+	case <-w.changed:
+		return false
+	case <-timeout:
+		return w.timeOutIfPastDeadline()
+	}
+}
+
+func (w *watchdog) timeOutIfPastDeadline() bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.deadline.IsZero() || time.Now().Before(w.deadline) {
+		return false
+	}
+	// Not marked as a fatal error, because the spec's goroutine may still
+	// be reading it. The spec will anyways not proceed any further.
+	w.spec.AddError(w.timeoutError())
+	w.timedOut = true
+	return true
+}
+
+func (w *watchdog) timeoutError() *Error {
+	state, callers := goroutineDump(w.goroutine)
+	message := fmt.Sprintf("Timed out after %v", w.expiring.timeout)
+	if w.expiring.timeout != w.spec.timeout {
+		message = fmt.Sprintf("Timed out after %v of %v", w.expiring.timeout, w.expiring.name)
+	}
+	if state != "" {
+		message += fmt.Sprintf(" [%v]", state)
+	}
+	e := &exception{message, cutStackTraceAtFunction(functionName(recoverOnPanic), callers)}
+	w.spec.fixupStackTraceForRootSpec(e)
+	return newError(TimedOut, message, "", e.StackTrace)
+}
+
+// How long the clean up after the specs waits for the goroutines of the timed
+// out specs to stop. A goroutine which is blocked forever never stops.
+const timedOutGracePeriod = time.Second
+
+func waitUntilStopped(stillRunning []<-chan bool) {
+	deadline := time.After(timedOutGracePeriod)
+	for _, done := range stillRunning {
+		select {
+		case <-done:
+		case <-deadline:
+			return
+		}
+	}
+}
+
+func cutStackTraceAtFunction(name string, callers []*Location) []*Location {
+	for i, loc := range callers {
+		if loc.Name() == name {
+			return callers[0:i]
+		}
+	}
+	return callers
+}
+
+func currentGoroutineId() int {
+	buf := make([]byte, 64)
+	buf = buf[0:runtime.Stack(buf, false)]
+	id, _ := parseGoroutineHeader(strings.SplitN(string(buf), "\n", 2)[0])
+	return id
+}
+
+// Returns the state and the stack trace of a goroutine, as reported by
+// runtime.Stack, whose format is like this:
+//
+//	goroutine 6 [chan receive]:
+//	main.stuck(...)
+//		/tmp/main.go:5
+//	main.main.func1()
+//		/tmp/main.go:8 +0x25
+//	created by main.main in goroutine 1
+//		/tmp/main.go:8 +0x1e
+func goroutineDump(goroutine int) (state string, stackTrace []*Location) {
+	for _, dump := range strings.Split(allGoroutineDumps(), "\n\n") {
+		lines := strings.Split(strings.TrimSpace(dump), "\n")
+		if id, state := parseGoroutineHeader(lines[0]); id == goroutine {
+			return state, parseStackTrace(lines[1:])
+		}
+	}
+	return "", []*Location{}
+}
+
+func allGoroutineDumps() string {
+	buf := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return string(buf[0:n])
+		}
+		buf = make([]byte, len(buf)*2)
+	}
+}
+
+func parseGoroutineHeader(header string) (id int, state string) {
+	header = strings.TrimPrefix(header, "goroutine ")
+	header = strings.TrimSuffix(header, ":")
+	parts := strings.SplitN(header, " ", 2)
+	id, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return -1, ""
+	}
+	if len(parts) == 2 {
+		state = strings.Trim(parts[1], "[]")
+	}
+	return id, state
+}
+
+func parseStackTrace(lines []string) []*Location {
+	result := []*Location{}
+	for i := 0; i+1 < len(lines); i += 2 {
+		function, position := lines[i], strings.TrimSpace(lines[i+1])
+		if strings.HasPrefix(function, "created by ") {
+			break
+		}
+		if paren := strings.LastIndex(function, "("); paren > 0 {
+			function = function[0:paren]
+		}
+		if offset := strings.LastIndex(position, " +0x"); offset > 0 {
+			position = position[0:offset]
+		}
+		colon := strings.LastIndex(position, ":")
+		line, err := strconv.Atoi(position[colon+1:])
+		if colon < 0 || err != nil {
+			continue
+		}
+		result = append(result, &Location{function, position[0:colon], line})
+	}
+	return result
+}
@@ -0,0 +1,150 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"github.com/orfjackal/nanospec.go/src/nanospec"
+	"time"
+)
+
+const (
+	TIMEOUT = 100 * MILLISECOND
+)
+
+func TimeoutSpec(c nanospec.Context) {
+
+	c.Specify("When a spec is blocked for longer than the timeout", func() {
+		runner := NewRunner()
+		runner.SetTimeout(TIMEOUT)
+		runner.AddNamedSpec("RootSpec", DummySpecWhichBlocksForever)
+		runner.Run()
+		results := runner.Results()
+
+		c.Specify("then the spec fails with a timeout error", func() {
+			c.Expect(results).Matches(ReportContains(`
+  - Blocked [FAIL]
+*** Timed out after 100ms [chan receive]
+    at timeout_test.go
+    at timeout_test.go
+`))
+		})
+		c.Specify("then the rest of the specs are executed normally", func() {
+			c.Expect(results).Matches(ReportContains(`
+  - Not blocked
+`))
+			c.Expect(results.FailCount()).Equals(1)
+		})
+	})
+
+	c.Specify("The timeout can be overridden for a subtree of specs", func() {
+		runner := NewRunner()
+		runner.AddNamedSpec("RootSpec", func(c Context) {
+			c.SetTimeout(TIMEOUT)
+			DummySpecWhichBlocksForever(c)
+		})
+		runner.Run()
+
+		c.Expect(runner.Results()).Matches(ReportContains(`
+  - Blocked [FAIL]
+*** Timed out after 100ms [chan receive]
+`))
+	})
+
+	c.Specify("The timeout is measured from the start of the spec, including its children", func() {
+		// Each step leaves a wide margin to the timeout,
+		// but all of the steps together take longer than it.
+		const timeout = 300 * MILLISECOND
+		const step = 120 * MILLISECOND
+		runner := NewRunner()
+		runner.SetTimeout(timeout)
+		runner.AddNamedSpec("RootSpec", func(c Context) {
+			time.Sleep(step)
+			c.Specify("Child", func() {
+				time.Sleep(step)
+			})
+			time.Sleep(step)
+		})
+		runner.Run()
+		results := runner.Results()
+
+		c.Expect(results).Matches(ReportContains(`- RootSpec [FAIL]
+*** Timed out after 300ms [sleep]
+`))
+		c.Expect(results.FailCount()).Equals(1)
+	})
+
+	c.Specify("When a parent spec times out, the child which is executing fails", func() {
+		runner := NewRunner()
+		runner.AddNamedSpec("RootSpec", func(c Context) {
+			c.Specify("Parent", func() {
+				c.SetTimeout(TIMEOUT)
+				c.Specify("Blocked child", func() {
+					c.SetTimeout(10 * TIMEOUT)
+					blockForever()
+				})
+			})
+		})
+		runner.Run()
+		results := runner.Results()
+
+		c.Expect(results).Matches(ReportContains(`
+    - Blocked child [FAIL]
+*** Timed out after 100ms of Parent [chan receive]
+`))
+		c.Expect(results.FailCount()).Equals(1)
+	})
+
+	c.Specify("The shared fixture is closed after the goroutine of a timed out spec has stopped", func() {
+		fixture := &DummyFixture{}
+		closedWhileRunning := true
+		runner := NewRunner()
+		runner.SetTimeout(TIMEOUT)
+		runner.AddNamedSpecWithFixture("RootSpec",
+			func() (interface{}, error) { return fixture, nil },
+			func(c Context, value interface{}) {
+				c.Specify("Slow", func() {
+					time.Sleep(3 * TIMEOUT)
+					closedWhileRunning = fixture.closed
+					c.Expect(1, Equals, 1) // stops the goroutine
+				})
+			})
+		runner.Run()
+
+		c.Expect(fixture.closed).IsTrue()
+		c.Expect(closedWhileRunning).IsFalse()
+	})
+
+	c.Specify("Goroutine dumps are parsed into stack traces", func() {
+		state, _ := goroutineDump(currentGoroutineId())
+		c.Expect(state).Equals("running")
+
+		stackTrace := parseStackTrace([]string{
+			"main.stuck(...)",
+			"	/tmp/main.go:5",
+			"main.(*Foo).bar(0xc000012345)",
+			"	/tmp/main.go:8 +0x25",
+			"created by main.main in goroutine 1",
+			"	/tmp/main.go:8 +0x1e",
+		})
+		c.Expect(len(stackTrace)).Equals(2)
+		c.Expect(stackTrace[0].Name()).Equals("main.stuck")
+		c.Expect(stackTrace[0].File()).Equals("/tmp/main.go")
+		c.Expect(stackTrace[0].Line()).Equals(5)
+		c.Expect(stackTrace[1].Name()).Equals("main.(*Foo).bar")
+		c.Expect(stackTrace[1].Line()).Equals(8)
+	})
+}
+
+func DummySpecWhichBlocksForever(c Context) {
+	c.Specify("Blocked", func() {
+		blockForever()
+	})
+	c.Specify("Not blocked", func() {
+	})
+}
+
+func blockForever() {
+	<-make(chan bool)
+}
@@ -0,0 +1,124 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"bytes"
+	"github.com/orfjackal/nanospec.go/src/nanospec"
+	"strings"
+	"time"
+)
+
+const (
+	SLOW = 10 * MILLISECOND
+)
+
+func durationsByName(results *ResultCollector) map[string]time.Duration {
+	durations := make(map[string]time.Duration)
+	results.visitAll(func(spec *specResult) {
+		durations[spec.name] = spec.duration
+	})
+	return durations
+}
+
+func TimingSpec(c nanospec.Context) {
+
+	c.Specify("The duration of every spec is recorded", func() {
+		results := runSpec(func(c Context) {
+			c.Specify("Slow", func() {
+				time.Sleep(SLOW)
+			})
+			c.Specify("Fast", func() {})
+		})
+		durations := durationsByName(results)
+		c.Expect(durations["Slow"] >= SLOW).IsTrue()
+		c.Expect(durations["Fast"] < SLOW).IsTrue()
+	})
+
+	c.Specify("The duration of a parent spec includes all its runs", func() {
+		results := runSpec(func(c Context) {
+			time.Sleep(SLOW)
+			c.Specify("Child 1", func() {})
+			c.Specify("Child 2", func() {})
+		})
+		// the root spec is executed once for each child
+		c.Expect(durationsByName(results)["RootSpec"] >= 2*SLOW).IsTrue()
+	})
+
+	c.Specify("The duration of a spec which times out is until the timeout", func() {
+		r := NewRunner()
+		r.SetTimeout(SLOW)
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			c.Specify("Blocked", func() {
+				select {}
+			})
+		})
+		r.Run()
+		c.Expect(durationsByName(r.Results())["Blocked"] >= SLOW).IsTrue()
+	})
+
+	c.Specify("The durations are saved with the results", func() {
+		results := runSpec(func(c Context) {
+			c.Specify("Slow", func() {
+				time.Sleep(SLOW)
+			})
+		})
+		c.Expect(durationsByName(saveAndLoad(results))).Equals(durationsByName(results))
+	})
+
+	c.Specify("When reporting the slowest specs", func() {
+		r := NewRunner()
+		r.AddNamedSpec("SlowSpec", func(c Context) {
+			c.Specify("Slow parent", func() {
+				c.Specify("Slowest", func() {
+					time.Sleep(5 * SLOW)
+				})
+				c.Specify("Slow", func() {
+					time.Sleep(SLOW)
+				})
+			})
+		})
+		r.AddNamedSpec("FastSpec", func(c Context) {
+			c.Specify("Fast", func() {})
+		})
+		r.Run()
+		results := r.Results()
+		results.ReportSlowest(2)
+		summary := results.summary()
+
+		c.Specify("only the specs without children are listed, with the names of their parents", func() {
+			c.Expect(len(summary.SlowestSpecs)).Equals(2)
+			c.Expect(summary.SlowestSpecs[0].Name).Equals("SlowSpec / Slow parent / Slowest")
+			c.Expect(summary.SlowestSpecs[1].Name).Equals("SlowSpec / Slow parent / Slow")
+		})
+		c.Specify("the root specs are listed separately", func() {
+			c.Expect(len(summary.SlowestRoots)).Equals(2)
+			c.Expect(summary.SlowestRoots[0].Name).Equals("SlowSpec")
+			c.Expect(summary.SlowestRoots[1].Name).Equals("FastSpec")
+		})
+		c.Specify("they are not listed unless requested", func() {
+			results.ReportSlowest(0)
+			c.Expect(len(results.summary().SlowestSpecs)).Equals(0)
+		})
+	})
+
+	c.Specify("The slowest specs are printed after the summary", func() {
+		out := new(bytes.Buffer)
+		DefaultPrintFormat(out).PrintSummary(Summary{
+			PassCount:    2,
+			SlowestSpecs: []SpecTiming{{"RootSpec / Slow", 1500 * time.Millisecond}},
+			SlowestRoots: []SpecTiming{{"RootSpec", 2 * time.Second}},
+		})
+		c.Expect(strings.TrimSpace(out.String())).Equals(strings.TrimSpace(`
+2 specs, 0 failures
+
+Slowest specs:
+    1.500s  RootSpec / Slow
+
+Slowest root specs:
+    2.000s  RootSpec
+`))
+	})
+}
@@ -1,5 +1,7 @@
 package hello
 
+//go:generate gospecgen
+
 import (
 	"github.com/orfjackal/gospec/src/gospec"
 	. "github.com/orfjackal/gospec/src/gospec"